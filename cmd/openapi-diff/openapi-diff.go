@@ -0,0 +1,160 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// This program compares two OpenAPI specs of the same API, given as JSON, YAML or gnostic protobuf files, and
+// reports the changes between them, so that it can gate API reviews. It exits with
+//   - 0 if no change breaks clients,
+//   - 1 if any change breaks clients,
+//   - 2 on wrong usage or if the specs cannot be read.
+//
+// Usage: openapi-diff [flags] OLD_SPEC NEW_SPEC
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ghodss/yaml"
+	"github.com/go-openapi/spec"
+	"github.com/spf13/pflag"
+
+	"k8s.io/kube-openapi/pkg/aggregator"
+	"k8s.io/kube-openapi/pkg/diff"
+)
+
+// Exit codes of the program.
+const (
+	exitOK       = 0
+	exitBreaking = 1
+	exitError    = 2
+)
+
+func main() {
+	os.Exit(run(os.Args, os.Stdout, os.Stderr))
+}
+
+// run runs the program with the given command line, and returns its exit code.
+func run(args []string, stdout, stderr io.Writer) int {
+	flags := pflag.NewFlagSet(args[0], pflag.ContinueOnError)
+	flags.SetOutput(stderr)
+	ignoreDocumentation := flags.Bool("ignore-documentation", false, "Do not report changes of descriptions, titles, summaries and examples.")
+	pathPrefixes := flags.StringSlice("path-prefix", nil, "Only compare paths with one of these prefixes, and the definitions they use. Can be repeated.")
+	output := flags.StringP("output", "o", "text", "Output format, one of text or json.")
+	flags.Usage = func() {
+		fmt.Fprintf(stderr, "Usage: %s [flags] OLD_SPEC NEW_SPEC\n", args[0])
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args[1:]); err != nil {
+		if err == pflag.ErrHelp {
+			return exitOK
+		}
+		return exitError
+	}
+	if flags.NArg() != 2 {
+		flags.Usage()
+		return exitError
+	}
+	if *output != "text" && *output != "json" {
+		fmt.Fprintf(stderr, "unknown output format %q, must be text or json\n", *output)
+		return exitError
+	}
+
+	oldSpec, err := readSpec(flags.Arg(0))
+	if err != nil {
+		fmt.Fprintf(stderr, "error reading old spec: %v\n", err)
+		return exitError
+	}
+	newSpec, err := readSpec(flags.Arg(1))
+	if err != nil {
+		fmt.Fprintf(stderr, "error reading new spec: %v\n", err)
+		return exitError
+	}
+	if len(*pathPrefixes) > 0 {
+		oldSpec = aggregator.FilterSpecByPathsWithoutSideEffects(oldSpec, *pathPrefixes)
+		newSpec = aggregator.FilterSpecByPathsWithoutSideEffects(newSpec, *pathPrefixes)
+	}
+
+	report := diff.Compare(oldSpec, newSpec)
+	if *ignoreDocumentation {
+		report = report.WithoutDocumentation()
+	}
+	if *output == "json" {
+		err = writeJSON(stdout, report)
+	} else {
+		err = writeText(stdout, report)
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "error writing report: %v\n", err)
+		return exitError
+	}
+	if report.HasBreakingChanges() {
+		return exitBreaking
+	}
+	return exitOK
+}
+
+// readSpec reads a spec from a file. Files ending in .pb or .proto are gnostic OpenAPI v2 protobuf documents, and
+// all other files JSON or YAML.
+func readSpec(path string) (*spec.Swagger, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".pb", ".proto":
+		data, err = protoToJSON(data)
+	default:
+		data, err = yaml.YAMLToJSON(data)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error decoding %s: %v", path, err)
+	}
+	sp := &spec.Swagger{}
+	if err := json.Unmarshal(data, sp); err != nil {
+		return nil, fmt.Errorf("error decoding %s: %v", path, err)
+	}
+	return sp, nil
+}
+
+func writeJSON(w io.Writer, report *diff.Report) error {
+	if report.Changes == nil {
+		report.Changes = []diff.Change{}
+	}
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "%s\n", data)
+	return err
+}
+
+func writeText(w io.Writer, report *diff.Report) error {
+	counts := map[diff.Severity]int{}
+	for _, c := range report.Changes {
+		counts[c.Severity]++
+		if _, err := fmt.Fprintln(w, c.String()); err != nil {
+			return err
+		}
+	}
+	_, err := fmt.Fprintf(w, "%d breaking, %d non-breaking and %d documentation changes\n",
+		counts[diff.Breaking], counts[diff.NonBreaking], counts[diff.Documentation])
+	return err
+}
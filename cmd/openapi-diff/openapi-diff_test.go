@@ -0,0 +1,231 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ghodss/yaml"
+	"github.com/go-openapi/spec"
+	"github.com/golang/protobuf/proto"
+	"github.com/googleapis/gnostic/OpenAPIv2"
+	"github.com/googleapis/gnostic/compiler"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	yamlv2 "gopkg.in/yaml.v2"
+
+	"k8s.io/kube-openapi/pkg/diff"
+)
+
+const oldSpec = `{
+  "swagger": "2.0",
+  "info": {"title": "Test", "version": "v1"},
+  "paths": {
+    "/apis/a/foo": {
+      "get": {
+        "operationId": "getFoo",
+        "tags": ["a"],
+        "parameters": [{"name": "limit", "in": "query", "type": "integer", "minimum": 1}],
+        "responses": {"200": {"description": "OK", "schema": {"$ref": "#/definitions/Foo"}}}
+      }
+    },
+    "/apis/b/bar": {
+      "get": {
+        "operationId": "getBar",
+        "responses": {"200": {"description": "OK", "schema": {"type": "array", "items": {"type": "string"}}}}
+      }
+    }
+  },
+  "definitions": {
+    "Foo": {
+      "description": "A foo.",
+      "type": "object",
+      "required": ["name"],
+      "properties": {
+        "name": {"type": "string", "x-kubernetes-patch-strategy": "merge"},
+        "sizes": {"type": "array", "items": {"type": "integer", "format": "int32"}},
+        "labels": {"type": "object", "additionalProperties": {"type": "string"}},
+        "phase": {"type": "string", "enum": ["Pending", "Running"], "default": "Pending", "example": "Running"}
+      }
+    }
+  }
+}`
+
+// newSpec documents Foo differently and removes /apis/b/bar.
+const newSpec = `{
+  "swagger": "2.0",
+  "info": {"title": "Test", "version": "v1"},
+  "paths": {
+    "/apis/a/foo": {
+      "get": {
+        "operationId": "getFoo",
+        "tags": ["a"],
+        "parameters": [{"name": "limit", "in": "query", "type": "integer", "minimum": 1}],
+        "responses": {"200": {"description": "OK", "schema": {"$ref": "#/definitions/Foo"}}}
+      }
+    }
+  },
+  "definitions": {
+    "Foo": {
+      "description": "The foo.",
+      "type": "object",
+      "required": ["name"],
+      "properties": {
+        "name": {"type": "string", "x-kubernetes-patch-strategy": "merge"},
+        "sizes": {"type": "array", "items": {"type": "integer", "format": "int32"}},
+        "labels": {"type": "object", "additionalProperties": {"type": "string"}},
+        "phase": {"type": "string", "enum": ["Pending", "Running"], "default": "Pending", "example": "Running"}
+      }
+    }
+  }
+}`
+
+func writeFile(t *testing.T, dir, name string, data []byte) string {
+	path := filepath.Join(dir, name)
+	require.NoError(t, ioutil.WriteFile(path, data, 0644))
+	return path
+}
+
+func toProto(t *testing.T, jsonSpec string) []byte {
+	var info yamlv2.MapSlice
+	require.NoError(t, yamlv2.Unmarshal([]byte(jsonSpec), &info))
+	document, err := openapi_v2.NewDocument(info, compiler.NewContext("$root", nil))
+	require.NoError(t, err)
+	data, err := proto.Marshal(document)
+	require.NoError(t, err)
+	return data
+}
+
+func TestReadSpec(t *testing.T) {
+	dir, err := ioutil.TempDir("", "openapi-diff")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	expected := &spec.Swagger{}
+	require.NoError(t, json.Unmarshal([]byte(oldSpec), expected))
+	yamlSpec, err := yaml.JSONToYAML([]byte(oldSpec))
+	require.NoError(t, err)
+
+	for _, path := range []string{
+		writeFile(t, dir, "spec.json", []byte(oldSpec)),
+		writeFile(t, dir, "spec.yaml", yamlSpec),
+		writeFile(t, dir, "spec.pb", toProto(t, oldSpec)),
+	} {
+		actual, err := readSpec(path)
+		if assert.NoError(t, err, path) {
+			assert.Equal(t, expected, actual, path)
+		}
+	}
+
+	_, err = readSpec(writeFile(t, dir, "invalid.pb", []byte("not protobuf")))
+	assert.Error(t, err)
+	_, err = readSpec(filepath.Join(dir, "missing.json"))
+	assert.Error(t, err)
+}
+
+func TestRun(t *testing.T) {
+	dir, err := ioutil.TempDir("", "openapi-diff")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	oldPath := writeFile(t, dir, "old.json", []byte(oldSpec))
+	newPath := writeFile(t, dir, "new.pb", toProto(t, newSpec))
+
+	for _, tc := range []struct {
+		name     string
+		args     []string
+		exitCode int
+		stdout   string
+	}{
+		{
+			name:     "breaking changes",
+			args:     []string{oldPath, newPath},
+			exitCode: 1,
+			stdout: "documentation: /definitions/Foo: description changed\n" +
+				"breaking: /paths/~1apis~1b~1bar: path removed\n" +
+				"1 breaking, 0 non-breaking and 1 documentation changes\n",
+		},
+		{
+			name:     "ignore documentation",
+			args:     []string{"--ignore-documentation", oldPath, newPath},
+			exitCode: 1,
+			stdout: "breaking: /paths/~1apis~1b~1bar: path removed\n" +
+				"1 breaking, 0 non-breaking and 0 documentation changes\n",
+		},
+		{
+			name:     "path prefix",
+			args:     []string{"--path-prefix", "/apis/a/", oldPath, newPath},
+			exitCode: 0,
+			stdout: "documentation: /definitions/Foo: description changed\n" +
+				"0 breaking, 0 non-breaking and 1 documentation changes\n",
+		},
+		{
+			name:     "identical specs",
+			args:     []string{"--ignore-documentation", "--path-prefix", "/apis/a/", oldPath, newPath},
+			exitCode: 0,
+			stdout:   "0 breaking, 0 non-breaking and 0 documentation changes\n",
+		},
+		{
+			name:     "missing argument",
+			args:     []string{oldPath},
+			exitCode: 2,
+		},
+		{
+			name:     "unknown flag",
+			args:     []string{"--unknown", oldPath, newPath},
+			exitCode: 2,
+		},
+		{
+			name:     "unknown output format",
+			args:     []string{"-o", "xml", oldPath, newPath},
+			exitCode: 2,
+		},
+		{
+			name:     "unreadable spec",
+			args:     []string{oldPath, filepath.Join(dir, "missing.json")},
+			exitCode: 2,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			exitCode := run(append([]string{"openapi-diff"}, tc.args...), &stdout, &stderr)
+			assert.Equal(t, tc.exitCode, exitCode, stderr.String())
+			assert.Equal(t, tc.stdout, stdout.String())
+			if tc.exitCode == 2 {
+				assert.NotEmpty(t, stderr.String())
+			}
+		})
+	}
+}
+
+func TestRunJSONOutput(t *testing.T) {
+	dir, err := ioutil.TempDir("", "openapi-diff")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := writeFile(t, dir, "spec.json", []byte(oldSpec))
+
+	var stdout, stderr bytes.Buffer
+	assert.Equal(t, 0, run([]string{"openapi-diff", "-o", "json", path, path}, &stdout, &stderr))
+	report := &diff.Report{}
+	if assert.NoError(t, json.Unmarshal(stdout.Bytes(), report)) {
+		assert.Equal(t, []diff.Change{}, report.Changes)
+	}
+}
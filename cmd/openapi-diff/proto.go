@@ -0,0 +1,166 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+
+	"github.com/ghodss/yaml"
+	"github.com/golang/protobuf/proto"
+	"github.com/googleapis/gnostic/OpenAPIv2"
+)
+
+// protoToJSON converts a gnostic OpenAPI v2 protobuf document back to JSON. Protobuf does not distinguish unset
+// fields from zero values, so zero numbers, false booleans and empty strings are left out.
+func protoToJSON(data []byte) ([]byte, error) {
+	document := &openapi_v2.Document{}
+	if err := proto.Unmarshal(data, document); err != nil {
+		return nil, err
+	}
+	v, err := messageToJSON(reflect.ValueOf(document))
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(v)
+}
+
+var (
+	anyType         = reflect.TypeOf(openapi_v2.Any{})
+	typeItemType    = reflect.TypeOf(openapi_v2.TypeItem{})
+	itemsItemType   = reflect.TypeOf(openapi_v2.ItemsItem{})
+	stringArrayType = reflect.TypeOf(openapi_v2.StringArray{})
+)
+
+// messageToJSON converts a value of a gnostic message to the value of its OpenAPI representation. Lists of
+// Named* messages become the properties of the object containing them, and messages made of a single oneof
+// become the value of the oneof.
+func messageToJSON(v reflect.Value) (interface{}, error) {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			return nil, nil
+		}
+		return messageToJSON(v.Elem())
+	case reflect.Slice:
+		ret := make([]interface{}, 0, v.Len())
+		for i := 0; i < v.Len(); i++ {
+			item, err := messageToJSON(v.Index(i))
+			if err != nil {
+				return nil, err
+			}
+			ret = append(ret, item)
+		}
+		return ret, nil
+	case reflect.Struct:
+	default:
+		return v.Interface(), nil
+	}
+
+	switch v.Type() {
+	case anyType:
+		var ret interface{}
+		err := yaml.Unmarshal([]byte(v.FieldByName("Yaml").String()), &ret)
+		return ret, err
+	case typeItemType, itemsItemType:
+		// A single type or items schema is not wrapped in a list.
+		list := v.Field(0)
+		if list.Len() == 1 {
+			return messageToJSON(list.Index(0))
+		}
+		return messageToJSON(list)
+	case stringArrayType:
+		return messageToJSON(v.Field(0))
+	}
+
+	ret := map[string]interface{}{}
+	for i := 0; i < v.NumField(); i++ {
+		field, value := v.Type().Field(i), v.Field(i)
+		if strings.HasPrefix(field.Name, "XXX_") {
+			continue
+		}
+		if _, isOneof := field.Tag.Lookup("protobuf_oneof"); isOneof {
+			if value.IsNil() {
+				return nil, nil
+			}
+			// The oneof holds a pointer to a wrapper struct with the single field set.
+			return messageToJSON(value.Elem().Elem().Field(0))
+		}
+		if isNamedList(field.Type) {
+			for j := 0; j < value.Len(); j++ {
+				named := value.Index(j).Elem()
+				item, err := messageToJSON(named.FieldByName("Value"))
+				if err != nil {
+					return nil, err
+				}
+				ret[named.FieldByName("Name").String()] = item
+			}
+			continue
+		}
+		if isZero(value) {
+			continue
+		}
+		item, err := messageToJSON(value)
+		if err != nil {
+			return nil, err
+		}
+		ret[jsonName(field)] = item
+	}
+	return ret, nil
+}
+
+// isNamedList returns true for lists of Named* messages, which represent maps.
+func isNamedList(t reflect.Type) bool {
+	if t.Kind() != reflect.Slice || t.Elem().Kind() != reflect.Ptr || t.Elem().Elem().Kind() != reflect.Struct {
+		return false
+	}
+	elem := t.Elem().Elem()
+	_, hasName := elem.FieldByName("Name")
+	_, hasValue := elem.FieldByName("Value")
+	return strings.HasPrefix(elem.Name(), "Named") && hasName && hasValue
+}
+
+func isZero(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		return v.IsNil()
+	case reflect.Slice, reflect.Map, reflect.String:
+		return v.Len() == 0
+	}
+	return reflect.DeepEqual(v.Interface(), reflect.Zero(v.Type()).Interface())
+}
+
+// jsonName returns the OpenAPI name of a message field, which the protobuf tag gives in camel case.
+func jsonName(field reflect.StructField) string {
+	var name, camelName string
+	for _, part := range strings.Split(field.Tag.Get("protobuf"), ",") {
+		switch {
+		case strings.HasPrefix(part, "name="):
+			name = strings.TrimPrefix(part, "name=")
+		case strings.HasPrefix(part, "json="):
+			camelName = strings.TrimPrefix(part, "json=")
+		}
+	}
+	if name == "_ref" {
+		return "$ref"
+	}
+	if camelName != "" {
+		return camelName
+	}
+	return name
+}
@@ -18,7 +18,6 @@ package aggregator
 
 import (
 	"fmt"
-	"reflect"
 	"strings"
 
 	"github.com/go-openapi/spec"
@@ -26,64 +25,111 @@ import (
 	"k8s.io/kube-openapi/pkg/util"
 )
 
-// usedDefinitionForSpec returns a map with all used definitions in the provided spec as keys and true as values.
-func usedDefinitionForSpec(root *spec.Swagger) map[string]bool {
-	usedDefinitions := map[string]bool{}
+// usedReferences holds the names of the definitions, shared parameters and shared responses used by a spec.
+type usedReferences struct {
+	definitions, parameters, responses map[string]bool
+}
+
+// usedReferencesForSpec returns the definitions, shared parameters and shared responses reachable from the paths of the spec.
+func usedReferencesForSpec(root *spec.Swagger) usedReferences {
+	used := usedReferences{
+		definitions: map[string]bool{},
+		parameters:  map[string]bool{},
+		responses:   map[string]bool{},
+	}
 	walkOnAllReferences(func(ref *spec.Ref) {
-		if refStr := ref.String(); refStr != "" && strings.HasPrefix(refStr, definitionPrefix) {
-			usedDefinitions[refStr[len(definitionPrefix):]] = true
+		refStr := ref.String()
+		switch {
+		case strings.HasPrefix(refStr, definitionPrefix):
+			used.definitions[refStr[len(definitionPrefix):]] = true
+		case strings.HasPrefix(refStr, parameterPrefix):
+			used.parameters[refStr[len(parameterPrefix):]] = true
+		case strings.HasPrefix(refStr, responsePrefix):
+			used.responses[refStr[len(responsePrefix):]] = true
 		}
 	}, root)
-	return usedDefinitions
+	return used
+}
+
+// usedDefinitionForSpec returns a map with all used definitions in the provided spec as keys and true as values.
+func usedDefinitionForSpec(root *spec.Swagger) map[string]bool {
+	return usedReferencesForSpec(root).definitions
 }
 
 // FilterSpecByPaths removes unnecessary paths and definitions used by those paths.
-// i.e. if a Path removed by this function, all definitions used by it and not used
-// anywhere else will also be removed.
+// i.e. if a Path removed by this function, all definitions, shared parameters and shared
+// responses used by it and not used anywhere else will also be removed.
 func FilterSpecByPaths(sp *spec.Swagger, keepPathPrefixes []string) {
 	*sp = *FilterSpecByPathsWithoutSideEffects(sp, keepPathPrefixes)
 }
 
 // FilterSpecByPathsWithoutSideEffects removes unnecessary paths and definitions used by those paths.
-// i.e. if a Path removed by this function, all definitions used by it and not used
-// anywhere else will also be removed.
+// i.e. if a Path removed by this function, all definitions, shared parameters and shared
+// responses used by it and not used anywhere else will also be removed.
 // It does not modify the input, but the output shares data structures with the input.
 func FilterSpecByPathsWithoutSideEffects(sp *spec.Swagger, keepPathPrefixes []string) *spec.Swagger {
+	prefixes := util.NewTrie(keepPathPrefixes)
+	return filterPaths(sp, func(path string, pathItem spec.PathItem) (spec.PathItem, bool) {
+		return pathItem, prefixes.HasPrefix(path)
+	})
+}
+
+// filterPaths replaces every path item by the one returned by keep, or removes it if keep returns false,
+// and removes the definitions, shared parameters and shared responses no longer used. keep must not
+// mutate the path item it is passed. It does not modify the input, but the output shares data structures
+// with the input.
+func filterPaths(sp *spec.Swagger, keep func(path string, pathItem spec.PathItem) (spec.PathItem, bool)) *spec.Swagger {
 	if sp.Paths == nil {
 		return sp
 	}
 
-	// Walk all references to find all used definitions. This function
-	// want to only deal with unused definitions resulted from filtering paths.
-	// Thus a definition will be removed only if it has been used before but
+	// Walk all references to find all used definitions, parameters and responses. This function
+	// want to only deal with unused ones resulted from filtering paths.
+	// Thus a definition (parameter, response) will be removed only if it has been used before but
 	// it is unused because of a path prune.
-	initialUsedDefinitions := usedDefinitionForSpec(sp)
+	initialUsed := usedReferencesForSpec(sp)
 
 	// First remove unwanted paths
-	prefixes := util.NewTrie(keepPathPrefixes)
 	ret := *sp
 	ret.Paths = &spec.Paths{
 		VendorExtensible: sp.Paths.VendorExtensible,
 		Paths:            map[string]spec.PathItem{},
 	}
 	for path, pathItem := range sp.Paths.Paths {
-		if !prefixes.HasPrefix(path) {
-			continue
+		if pathItem, ok := keep(path, pathItem); ok {
+			ret.Paths.Paths[path] = pathItem
 		}
-		ret.Paths.Paths[path] = pathItem
 	}
 
-	// Walk all references to find all definition references.
-	usedDefinitions := usedDefinitionForSpec(&ret)
+	// Walk all references to find all definition, parameter and response references.
+	used := usedReferencesForSpec(&ret)
 
 	// Remove unused definitions
 	ret.Definitions = spec.Definitions{}
 	for k, v := range sp.Definitions {
-		if usedDefinitions[k] || !initialUsedDefinitions[k] {
+		if used.definitions[k] || !initialUsed.definitions[k] {
 			ret.Definitions[k] = v
 		}
 	}
 
+	// Remove unused shared parameters and responses
+	if sp.Parameters != nil {
+		ret.Parameters = map[string]spec.Parameter{}
+		for k, v := range sp.Parameters {
+			if used.parameters[k] || !initialUsed.parameters[k] {
+				ret.Parameters[k] = v
+			}
+		}
+	}
+	if sp.Responses != nil {
+		ret.Responses = map[string]spec.Response{}
+		for k, v := range sp.Responses {
+			if used.responses[k] || !initialUsed.responses[k] {
+				ret.Responses[k] = v
+			}
+		}
+	}
+
 	return &ret
 }
 
@@ -94,12 +140,27 @@ type rename struct {
 // renameDefinition renames references, without mutating the input.
 // The output might share data structures with the input.
 func renameDefinition(s *spec.Swagger, renames map[string]string) *spec.Swagger {
+	return renameReferences(s, definitionPrefix, renames)
+}
+
+// renameReferences renames the definitions, shared parameters or shared responses, depending on the
+// given reference prefix, together with all references to them, without mutating the input.
+// The output might share data structures with the input.
+func renameReferences(s *spec.Swagger, prefix string, renames map[string]string) *spec.Swagger {
 	refRenames := make(map[string]string, len(renames))
 	foundOne := false
 	for k, v := range renames {
-		refRenames[definitionPrefix+k] = definitionPrefix + v
-		if _, ok := s.Definitions[k]; ok {
-			foundOne = true
+		refRenames[prefix+k] = prefix + v
+		switch prefix {
+		case definitionPrefix:
+			_, found := s.Definitions[k]
+			foundOne = foundOne || found
+		case parameterPrefix:
+			_, found := s.Parameters[k]
+			foundOne = foundOne || found
+		case responsePrefix:
+			_, found := s.Responses[k]
+			foundOne = foundOne || found
 		}
 	}
 
@@ -119,42 +180,78 @@ func renameDefinition(s *spec.Swagger, renames map[string]string) *spec.Swagger
 		return ref
 	}, ret)
 
-	renamedDefinitions := make(spec.Definitions, len(ret.Definitions))
-	for k, v := range ret.Definitions {
-		if newRef, found := renames[k]; found {
-			k = newRef
+	newName := func(k string) string {
+		if newName, found := renames[k]; found {
+			return newName
+		}
+		return k
+	}
+	switch prefix {
+	case definitionPrefix:
+		renamedDefinitions := make(spec.Definitions, len(ret.Definitions))
+		for k, v := range ret.Definitions {
+			renamedDefinitions[newName(k)] = v
+		}
+		ret.Definitions = renamedDefinitions
+	case parameterPrefix:
+		renamedParameters := make(map[string]spec.Parameter, len(ret.Parameters))
+		for k, v := range ret.Parameters {
+			renamedParameters[newName(k)] = v
 		}
-		renamedDefinitions[k] = v
+		ret.Parameters = renamedParameters
+	case responsePrefix:
+		renamedResponses := make(map[string]spec.Response, len(ret.Responses))
+		for k, v := range ret.Responses {
+			renamedResponses[newName(k)] = v
+		}
+		ret.Responses = renamedResponses
 	}
-	ret.Definitions = renamedDefinitions
 
 	return ret
 }
 
 // MergeSpecsIgnorePathConflict is the same as MergeSpecs except it will ignore any path
-// conflicts by keeping the paths of destination. It will rename definition conflicts.
+// conflicts by keeping the paths of destination. It will rename definition conflicts, as well
+// as conflicts of shared parameters, responses and security definitions.
 // The source is not mutated.
 func MergeSpecsIgnorePathConflict(dest, source *spec.Swagger) error {
-	return mergeSpecs(dest, source, true, true)
+	_, err := MergeSpecsWithOptions(dest, source, MergeOptions{IgnorePathConflicts: true})
+	return err
 }
 
 // MergeSpecsFailOnDefinitionConflict is differ from MergeSpecs as it fails if there is
-// a definition conflict.
+// a definition conflict, or a conflict of shared parameters, responses or security definitions.
 // The source is not mutated.
 func MergeSpecsFailOnDefinitionConflict(dest, source *spec.Swagger) error {
-	return mergeSpecs(dest, source, false, false)
+	_, err := MergeSpecsWithOptions(dest, source, MergeOptions{FailOnConflicts: true})
+	return err
 }
 
-// MergeSpecs copies paths and definitions from source to dest, rename definitions if needed.
-// dest will be mutated, and source will not be changed. It will fail on path conflicts.
+// MergeSpecs copies paths, definitions, shared parameters and responses, security definitions and tags
+// from source to dest, rename them if needed. Tags are identified by name and never renamed, a tag of
+// dest keeps its description. If dest has neither paths nor default security requirements, it adopts the
+// ones of source, so the resulting default security depends on the merge order. dest will be mutated, and
+// source will not be changed. It will fail on path conflicts.
 // The source is not mutated.
 func MergeSpecs(dest, source *spec.Swagger) error {
-	return mergeSpecs(dest, source, true, false)
+	_, err := MergeSpecsWithOptions(dest, source, MergeOptions{})
+	return err
+}
+
+// MergeSpecsWithOptions merges source into dest like MergeSpecs, resolving conflicts as configured by the
+// options, and returns a report of what was merged and how. dest will be mutated, and source will not be changed.
+func MergeSpecsWithOptions(dest, source *spec.Swagger, opts MergeOptions) (*MergeReport, error) {
+	report := &MergeReport{Source: opts.SourceName}
+	if err := mergeSpecs(dest, source, &opts, report); err != nil {
+		return nil, err
+	}
+	report.sort()
+	return report, nil
 }
 
-// mergeSpecs merged source into dest while resolving conflicts.
+// mergeSpecs merged source into dest while resolving conflicts, and records what it did in the report.
 // The source is not mutated.
-func mergeSpecs(dest, source *spec.Swagger, renameModelConflicts, ignorePathConflicts bool) (err error) {
+func mergeSpecs(dest, source *spec.Swagger, opts *MergeOptions, report *MergeReport) (err error) {
 	// Paths may be empty, due to [ACL constraints](http://goo.gl/8us55a#securityFiltering).
 	if source.Paths == nil {
 		// When a source spec does not have any path, that means none of the definitions
@@ -164,96 +261,90 @@ func mergeSpecs(dest, source *spec.Swagger, renameModelConflicts, ignorePathConf
 	if dest.Paths == nil {
 		dest.Paths = &spec.Paths{}
 	}
-	if ignorePathConflicts {
+	if opts.MergeOperations {
+		if conflicts := operationConflicts(dest, source); len(conflicts) > 0 {
+			if !opts.IgnorePathConflicts {
+				return fmt.Errorf("unable to merge: duplicated operation %s %s", strings.ToUpper(conflicts[0].Method), conflicts[0].Path)
+			}
+			report.SkippedOperations = conflicts
+			source = dropOperations(source, conflicts)
+			if len(source.Paths.Paths) == 0 {
+				// There is nothing to merge. All operations are conflicting.
+				return nil
+			}
+		}
+	} else if opts.IgnorePathConflicts {
 		keepPaths := []string{}
-		hasConflictingPath := false
 		for k := range source.Paths.Paths {
 			if _, found := dest.Paths.Paths[k]; !found {
 				keepPaths = append(keepPaths, k)
 			} else {
-				hasConflictingPath = true
+				report.SkippedPaths = append(report.SkippedPaths, k)
 			}
 		}
 		if len(keepPaths) == 0 {
 			// There is nothing to merge. All paths are conflicting.
 			return nil
 		}
-		if hasConflictingPath {
+		if len(report.SkippedPaths) > 0 {
 			source = FilterSpecByPathsWithoutSideEffects(source, keepPaths)
 		}
 	}
-	// Check for model conflicts
-	conflicts := false
-	for k, v := range source.Definitions {
-		v2, found := dest.Definitions[k]
-		if found && !reflect.DeepEqual(v, v2) {
-			if !renameModelConflicts {
-				return fmt.Errorf("model name conflict in merging OpenAPI spec: %s", k)
-			}
-			conflicts = true
-			break
+	// Resolve conflicts of all shared objects before modifying dest. Definitions go first, because
+	// renaming them changes the shared parameters and responses referencing them.
+	renames := make([]map[string]string, len(sharedObjectSections))
+	for i, objects := range sharedObjectSections {
+		if source, renames[i], err = resolveConflicts(dest, source, objects, opts, report); err != nil {
+			return err
 		}
 	}
-
-	if conflicts {
-		usedNames := map[string]bool{}
-		for k := range dest.Definitions {
-			usedNames[k] = true
+	// Check for path conflicts
+	for k := range source.Paths.Paths {
+		if _, found := dest.Paths.Paths[k]; found && !opts.MergeOperations {
+			return fmt.Errorf("unable to merge: duplicated path %s", k)
 		}
-		renames := map[string]string{}
-
-	OUTERLOOP:
-		for k, v := range source.Definitions {
-			if usedNames[k] {
-				v2, found := dest.Definitions[k]
-				// Reuse model if they are exactly the same.
-				if found && reflect.DeepEqual(v, v2) {
-					continue
-				}
+	}
 
-				// Reuse previously renamed model if one exists
-				var newName string
-				i := 1
-				for found {
-					i++
-					newName = fmt.Sprintf("%s_v%d", k, i)
-					v2, found = dest.Definitions[newName]
-					if found && reflect.DeepEqual(v, v2) {
-						renames[k] = newName
-						continue OUTERLOOP
-					}
-				}
+	if source, err = resolveOperationIDConflicts(dest, source, opts, report); err != nil {
+		return err
+	}
 
-				_, foundInSource := source.Definitions[newName]
-				for usedNames[newName] || foundInSource {
-					i++
-					newName = fmt.Sprintf("%s_v%d", k, i)
-					_, foundInSource = source.Definitions[newName]
-				}
-				renames[k] = newName
-				usedNames[newName] = true
-			}
+	// Security definitions might have been renamed, so the default requirements are merged afterwards.
+	source = mergeDefaultSecurity(dest, source)
+	for i, objects := range sharedObjectSections {
+		originalNames := make(map[string]string, len(renames[i]))
+		for from, to := range renames[i] {
+			originalNames[to] = from
 		}
-		source = renameDefinition(source, renames)
-	}
-	for k, v := range source.Definitions {
-		if _, found := dest.Definitions[k]; !found {
-			if dest.Definitions == nil {
-				dest.Definitions = spec.Definitions{}
+		for _, k := range objects.names(source) {
+			merged := MergedObject{Kind: objects.kind, Name: k}
+			if from, renamed := originalNames[k]; renamed {
+				merged = MergedObject{Kind: objects.kind, Name: from, NewName: k}
+			}
+			if objects.has(dest, k) {
+				report.Reused = append(report.Reused, merged)
+			} else {
+				objects.add(dest, source, k)
+				report.Added = append(report.Added, merged)
 			}
-			dest.Definitions[k] = v
 		}
 	}
-	// Check for path conflicts
 	for k, v := range source.Paths.Paths {
-		if _, found := dest.Paths.Paths[k]; found {
-			return fmt.Errorf("unable to merge: duplicated path %s", k)
-		}
 		// PathItem may be empty, due to [ACL constraints](http://goo.gl/8us55a#securityFiltering).
 		if dest.Paths.Paths == nil {
 			dest.Paths.Paths = map[string]spec.PathItem{}
 		}
+		if existing, found := dest.Paths.Paths[k]; found {
+			dest.Paths.Paths[k] = mergePathItems(existing, v)
+			for _, m := range pathItemOperations(&v) {
+				if *m.op != nil {
+					report.AddedOperations = append(report.AddedOperations, PathOperation{Path: k, Method: m.method})
+				}
+			}
+			continue
+		}
 		dest.Paths.Paths[k] = v
+		report.AddedPaths = append(report.AddedPaths, k)
 	}
 	return nil
 }
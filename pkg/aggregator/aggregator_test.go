@@ -1992,3 +1992,476 @@ func cloneSpec(source *spec.Swagger) (*spec.Swagger, error) {
 	}
 	return &ret, nil
 }
+
+func TestFilterSpecsWithSharedParametersAndResponses(t *testing.T) {
+	var spec1, spec1Filtered *spec.Swagger
+	yaml.Unmarshal([]byte(`
+swagger: "2.0"
+paths:
+  /test:
+    parameters:
+    - $ref: "#/parameters/pretty"
+    post:
+      parameters:
+      - $ref: "#/parameters/body"
+      responses:
+        404:
+          $ref: "#/responses/NotFound"
+  /othertest:
+    parameters:
+    - $ref: "#/parameters/pretty"
+    post:
+      parameters:
+      - $ref: "#/parameters/body2"
+      responses:
+        401:
+          $ref: "#/responses/Unauthorized"
+parameters:
+  pretty:
+    in: "query"
+    name: "pretty"
+    type: "string"
+  body:
+    in: "body"
+    name: "body"
+    schema:
+      $ref: "#/definitions/Test"
+  body2:
+    in: "body"
+    name: "body"
+    schema:
+      $ref: "#/definitions/Test2"
+  unused:
+    in: "query"
+    name: "unused"
+    type: "string"
+responses:
+  NotFound:
+    description: "Not found"
+    schema:
+      $ref: "#/definitions/Status"
+  Unauthorized:
+    description: "Unauthorized"
+    schema:
+      $ref: "#/definitions/Status2"
+definitions:
+  Test:
+    type: "object"
+  Test2:
+    type: "object"
+  Status:
+    type: "object"
+  Status2:
+    type: "object"
+`), &spec1)
+
+	yaml.Unmarshal([]byte(`
+swagger: "2.0"
+paths:
+  /test:
+    parameters:
+    - $ref: "#/parameters/pretty"
+    post:
+      parameters:
+      - $ref: "#/parameters/body"
+      responses:
+        404:
+          $ref: "#/responses/NotFound"
+parameters:
+  pretty:
+    in: "query"
+    name: "pretty"
+    type: "string"
+  body:
+    in: "body"
+    name: "body"
+    schema:
+      $ref: "#/definitions/Test"
+  unused:
+    in: "query"
+    name: "unused"
+    type: "string"
+responses:
+  NotFound:
+    description: "Not found"
+    schema:
+      $ref: "#/definitions/Status"
+definitions:
+  Test:
+    type: "object"
+  Status:
+    type: "object"
+`), &spec1Filtered)
+
+	ast := assert.New(t)
+	used := usedReferencesForSpec(spec1)
+	ast.Equal(map[string]bool{"Test": true, "Test2": true, "Status": true, "Status2": true}, used.definitions)
+	ast.Equal(map[string]bool{"pretty": true, "body": true, "body2": true}, used.parameters)
+	ast.Equal(map[string]bool{"NotFound": true, "Unauthorized": true}, used.responses)
+
+	origSpec1, _ := cloneSpec(spec1)
+	newSpec1 := FilterSpecByPathsWithoutSideEffects(spec1, []string{"/test"})
+	ast.Equal(DebugSpec{spec1Filtered}, DebugSpec{newSpec1})
+	ast.Equal(DebugSpec{origSpec1}, DebugSpec{spec1}, "unexpected mutation of input")
+}
+
+func TestRenameSharedParametersAndResponses(t *testing.T) {
+	var spec1, expected *spec.Swagger
+	yaml.Unmarshal([]byte(`
+swagger: "2.0"
+paths:
+  /test:
+    post:
+      parameters:
+      - $ref: "#/parameters/body"
+      responses:
+        404:
+          $ref: "#/responses/NotFound"
+parameters:
+  body:
+    in: "body"
+    name: "body"
+    schema:
+      $ref: "#/definitions/Test"
+responses:
+  NotFound:
+    description: "Not found"
+definitions:
+  Test:
+    type: "object"
+`), &spec1)
+	yaml.Unmarshal([]byte(`
+swagger: "2.0"
+paths:
+  /test:
+    post:
+      parameters:
+      - $ref: "#/parameters/body_v2"
+      responses:
+        404:
+          $ref: "#/responses/NotFound_v2"
+parameters:
+  body_v2:
+    in: "body"
+    name: "body"
+    schema:
+      $ref: "#/definitions/Test"
+responses:
+  NotFound_v2:
+    description: "Not found"
+definitions:
+  Test:
+    type: "object"
+`), &expected)
+
+	ast := assert.New(t)
+	origSpec1, _ := cloneSpec(spec1)
+	renamed := renameReferences(spec1, parameterPrefix, map[string]string{"body": "body_v2"})
+	renamed = renameReferences(renamed, responsePrefix, map[string]string{"NotFound": "NotFound_v2"})
+	ast.Equal(DebugSpec{expected}, DebugSpec{renamed})
+	ast.Equal(DebugSpec{origSpec1}, DebugSpec{spec1}, "unexpected mutation of input")
+}
+
+func TestMergeSpecsSharedObjects(t *testing.T) {
+	var spec1, spec2, expected *spec.Swagger
+	yaml.Unmarshal([]byte(`
+swagger: "2.0"
+security:
+- BearerToken: []
+paths:
+  /test:
+    get:
+      tags:
+      - "core"
+      parameters:
+      - $ref: "#/parameters/pretty"
+      responses:
+        404:
+          $ref: "#/responses/NotFound"
+parameters:
+  pretty:
+    in: "query"
+    name: "pretty"
+    type: "string"
+responses:
+  NotFound:
+    description: "Not found"
+securityDefinitions:
+  BearerToken:
+    type: "apiKey"
+    name: "authorization"
+    in: "header"
+tags:
+- name: "core"
+  description: "Core API"
+`), &spec1)
+	yaml.Unmarshal([]byte(`
+swagger: "2.0"
+security:
+- BearerToken: []
+paths:
+  /othertest:
+    get:
+      tags:
+      - "core"
+      - "other"
+      parameters:
+      - $ref: "#/parameters/pretty"
+      - $ref: "#/parameters/watch"
+      responses:
+        404:
+          $ref: "#/responses/NotFound"
+parameters:
+  pretty:
+    in: "query"
+    name: "pretty"
+    type: "boolean"
+  watch:
+    in: "query"
+    name: "watch"
+    type: "boolean"
+responses:
+  NotFound:
+    description: "Not found"
+securityDefinitions:
+  BearerToken:
+    type: "apiKey"
+    name: "x-token"
+    in: "header"
+tags:
+- name: "core"
+  description: "Other core API"
+- name: "other"
+`), &spec2)
+	yaml.Unmarshal([]byte(`
+swagger: "2.0"
+security:
+- BearerToken: []
+paths:
+  /test:
+    get:
+      tags:
+      - "core"
+      parameters:
+      - $ref: "#/parameters/pretty"
+      responses:
+        404:
+          $ref: "#/responses/NotFound"
+  /othertest:
+    get:
+      tags:
+      - "core"
+      - "other"
+      parameters:
+      - $ref: "#/parameters/pretty_v2"
+      - $ref: "#/parameters/watch"
+      responses:
+        404:
+          $ref: "#/responses/NotFound"
+      security:
+      - BearerToken_v2: []
+parameters:
+  pretty:
+    in: "query"
+    name: "pretty"
+    type: "string"
+  pretty_v2:
+    in: "query"
+    name: "pretty"
+    type: "boolean"
+  watch:
+    in: "query"
+    name: "watch"
+    type: "boolean"
+responses:
+  NotFound:
+    description: "Not found"
+securityDefinitions:
+  BearerToken:
+    type: "apiKey"
+    name: "authorization"
+    in: "header"
+  BearerToken_v2:
+    type: "apiKey"
+    name: "x-token"
+    in: "header"
+tags:
+- name: "core"
+  description: "Core API"
+- name: "other"
+`), &expected)
+
+	ast := assert.New(t)
+	origSpec2, _ := cloneSpec(spec2)
+	if !ast.NoError(MergeSpecs(spec1, spec2)) {
+		return
+	}
+	ast.Equal(DebugSpec{expected}, DebugSpec{spec1})
+	ast.Equal(DebugSpec{origSpec2}, DebugSpec{spec2}, "unexpected mutation of input")
+}
+
+func TestMergeSpecsFailOnSharedObjectConflict(t *testing.T) {
+	var spec1, spec2 *spec.Swagger
+	yaml.Unmarshal([]byte(`
+swagger: "2.0"
+paths:
+  /test:
+    get:
+      parameters:
+      - $ref: "#/parameters/pretty"
+parameters:
+  pretty:
+    in: "query"
+    name: "pretty"
+    type: "string"
+`), &spec1)
+	yaml.Unmarshal([]byte(`
+swagger: "2.0"
+paths:
+  /othertest:
+    get:
+      parameters:
+      - $ref: "#/parameters/pretty"
+parameters:
+  pretty:
+    in: "query"
+    name: "pretty"
+    type: "boolean"
+`), &spec2)
+
+	ast := assert.New(t)
+	ast.EqualError(MergeSpecsFailOnDefinitionConflict(spec1, spec2), "parameter name conflict in merging OpenAPI spec: pretty")
+	ast.NotContains(spec1.Paths.Paths, "/othertest", "unexpected mutation of dest on failure")
+}
+
+func TestMergeSpecsWithOptionsReport(t *testing.T) {
+	var dest, source *spec.Swagger
+	yaml.Unmarshal([]byte(`
+swagger: "2.0"
+paths:
+  /a:
+    get:
+      responses:
+        200:
+          description: "OK"
+          schema:
+            $ref: "#/definitions/Foo"
+definitions:
+  Foo:
+    type: "string"
+  Bar:
+    type: "string"
+`), &dest)
+	yaml.Unmarshal([]byte(`
+swagger: "2.0"
+paths:
+  /a:
+    get:
+      responses:
+        200:
+          description: "OK"
+          schema:
+            $ref: "#/definitions/Foo"
+  /b:
+    get:
+      responses:
+        200:
+          description: "OK"
+          schema:
+            $ref: "#/definitions/Foo"
+  /c:
+    get:
+      responses:
+        200:
+          description: "OK"
+          schema:
+            $ref: "#/definitions/Bar"
+  /d:
+    get:
+      responses:
+        200:
+          description: "OK"
+          schema:
+            $ref: "#/definitions/Baz"
+definitions:
+  Foo:
+    type: "integer"
+  Bar:
+    type: "string"
+  Baz:
+    type: "string"
+`), &source)
+
+	ast := assert.New(t)
+	report, err := MergeSpecsWithOptions(dest, source, MergeOptions{SourceName: "first", IgnorePathConflicts: true})
+	if !ast.NoError(err) {
+		return
+	}
+	ast.Equal(&MergeReport{
+		Source:       "first",
+		AddedPaths:   []string{"/b", "/c", "/d"},
+		SkippedPaths: []string{"/a"},
+		Added: []MergedObject{
+			{Kind: DefinitionKind, Name: "Baz"},
+			{Kind: DefinitionKind, Name: "Foo", NewName: "Foo_v2"},
+		},
+		Reused: []MergedObject{
+			{Kind: DefinitionKind, Name: "Bar"},
+		},
+		Renamed: []MergedObject{
+			{Kind: DefinitionKind, Name: "Foo", NewName: "Foo_v2", Reason: RenameReasonConflict},
+		},
+	}, report)
+
+	// Merging the same definitions again reuses the renamed definition.
+	source.Paths.Paths = map[string]spec.PathItem{"/e": source.Paths.Paths["/b"]}
+	report, err = MergeSpecsWithOptions(dest, source, MergeOptions{SourceName: "second"})
+	if !ast.NoError(err) {
+		return
+	}
+	ast.Equal(&MergeReport{
+		Source:     "second",
+		AddedPaths: []string{"/e"},
+		Reused: []MergedObject{
+			{Kind: DefinitionKind, Name: "Bar"},
+			{Kind: DefinitionKind, Name: "Baz"},
+			{Kind: DefinitionKind, Name: "Foo", NewName: "Foo_v2"},
+		},
+		Renamed: []MergedObject{
+			{Kind: DefinitionKind, Name: "Foo", NewName: "Foo_v2", Reason: RenameReasonReused},
+		},
+	}, report)
+}
+
+func TestMergeSpecsTagsByName(t *testing.T) {
+	var spec1, spec2 *spec.Swagger
+	yaml.Unmarshal([]byte(`
+swagger: "2.0"
+paths:
+  /test:
+    get:
+      tags:
+      - "core"
+tags:
+- name: "core"
+  description: "Core API"
+`), &spec1)
+	yaml.Unmarshal([]byte(`
+swagger: "2.0"
+paths:
+  /othertest:
+    get:
+      tags:
+      - "core"
+tags:
+- name: "core"
+  description: "Other core API"
+`), &spec2)
+
+	ast := assert.New(t)
+	if !ast.NoError(MergeSpecsFailOnDefinitionConflict(spec1, spec2)) {
+		return
+	}
+	ast.Equal([]spec.Tag{spec.NewTag("core", "Core API", nil)}, spec1.Tags)
+	ast.Equal([]string{"core"}, spec1.Paths.Paths["/othertest"].Get.Tags)
+}
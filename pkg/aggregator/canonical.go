@@ -0,0 +1,218 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aggregator
+
+import (
+	"encoding/json"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+
+	"github.com/go-openapi/spec"
+)
+
+// CanonicalOptions configures Canonicalize.
+type CanonicalOptions struct {
+	// CollapseRenameSuffixes renames definitions with a _v<N> suffix, as added by merges on conflicts, to the
+	// name without suffix if no definition has that name. Of several such definitions, the one with the smallest
+	// N is renamed. This keeps names stable when the conflicting definition disappears, but changes the names
+	// of definitions clients might rely on.
+	CollapseRenameSuffixes bool
+}
+
+// Canonicalize returns a SpecTransformer bringing specs into a canonical form, so that specs that only differ in
+// ways without meaning serialize to the same JSON. Besides everything SortAndNormalize does, it
+//   - removes empty lists and maps, including the top-level ones, and normalizes extension values to the
+//     types JSON decoding gives them,
+//   - sorts and deduplicates types of schemas, as well as consumes and produces of operations and the spec,
+//   - removes duplicate parameters and tags,
+//   - sorts and deduplicates the security requirements and their scopes, and removes security requirements
+//     of operations that equal the default ones of the spec.
+//
+// Canonicalize is idempotent.
+func Canonicalize(opts CanonicalOptions) SpecTransformer {
+	transformers := []SpecTransformer{roundTripJSON, SortAndNormalize(), canonicalize}
+	if opts.CollapseRenameSuffixes {
+		transformers = append(transformers, collapseRenameSuffixes)
+	}
+	// Sorting and renaming might leave empty lists and maps.
+	return ChainTransformers(append(transformers, roundTripJSON)...)
+}
+
+// roundTripJSON is a JSON round trip, dropping empty lists and maps and normalizing extension values.
+func roundTripJSON(sp *spec.Swagger) (*spec.Swagger, error) {
+	return deepCopySpec(sp)
+}
+
+// canonicalize does the part of Canonicalize not done by SortAndNormalize. It sorts the default security
+// requirements of its input in place, so it must only be called on copies.
+func canonicalize(sp *spec.Swagger) (*spec.Swagger, error) {
+	defaultSecurity := sortedSecurityRequirements(sp.Security)
+	ret, err := RewriteSpec(sp, &SpecVisitor{
+		Schema: func(_ string, s *spec.Schema) {
+			s.Type = spec.StringOrArray(sortedUnique(s.Type))
+		},
+		PathItem: func(_ string, pathItem *spec.PathItem) {
+			pathItem.Parameters = uniqueParameters(pathItem.Parameters)
+		},
+		Operation: func(_ string, op *spec.Operation) {
+			op.Parameters = uniqueParameters(op.Parameters)
+			op.Consumes = sortedUnique(op.Consumes)
+			op.Produces = sortedUnique(op.Produces)
+			op.Security = sortedSecurityRequirements(op.Security)
+			if op.Security != nil && reflect.DeepEqual(op.Security, defaultSecurity) {
+				op.Security = nil
+			}
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	ret.Consumes = sortedUnique(ret.Consumes)
+	ret.Produces = sortedUnique(ret.Produces)
+	ret.Security = defaultSecurity
+	ret.Tags = uniqueTags(ret.Tags)
+	if len(ret.Definitions) == 0 {
+		ret.Definitions = nil
+	}
+	if len(ret.Parameters) == 0 {
+		ret.Parameters = nil
+	}
+	if len(ret.Responses) == 0 {
+		ret.Responses = nil
+	}
+	if len(ret.SecurityDefinitions) == 0 {
+		ret.SecurityDefinitions = nil
+	}
+	return ret, nil
+}
+
+// uniqueParameters removes parameters deeply equal to an earlier parameter of the sorted list, in place.
+func uniqueParameters(params []spec.Parameter) []spec.Parameter {
+	if len(params) == 0 {
+		return nil
+	}
+	ret := params[:1]
+	for _, p := range params[1:] {
+		if !reflect.DeepEqual(p, ret[len(ret)-1]) {
+			ret = append(ret, p)
+		}
+	}
+	return ret
+}
+
+// uniqueTags removes tags deeply equal to an earlier tag of the list sorted by name, in place.
+func uniqueTags(tags []spec.Tag) []spec.Tag {
+	if len(tags) == 0 {
+		return nil
+	}
+	ret := tags[:1]
+	for _, t := range tags[1:] {
+		duplicate := false
+		for i := len(ret) - 1; i >= 0 && ret[i].Name == t.Name; i-- {
+			if reflect.DeepEqual(ret[i], t) {
+				duplicate = true
+				break
+			}
+		}
+		if !duplicate {
+			ret = append(ret, t)
+		}
+	}
+	return ret
+}
+
+// sortedSecurityRequirements sorts and deduplicates the scopes of each requirement in place, and returns the
+// requirements sorted and deduplicated by their JSON representation. Empty lists become nil.
+func sortedSecurityRequirements(requirements []map[string][]string) []map[string][]string {
+	if len(requirements) == 0 {
+		return nil
+	}
+	keys := make([]string, len(requirements))
+	for i, r := range requirements {
+		for name, scopes := range r {
+			if scopes = sortedUnique(scopes); scopes == nil {
+				// Requirements without scopes have an empty list.
+				scopes = []string{}
+			}
+			r[name] = scopes
+		}
+		bytes, _ := json.Marshal(r)
+		keys[i] = string(bytes)
+	}
+	sort.Sort(securityRequirementsByKey{requirements, keys})
+	ret := requirements[:1]
+	for i := 1; i < len(requirements); i++ {
+		if keys[i] != keys[i-1] {
+			ret = append(ret, requirements[i])
+		}
+	}
+	return ret
+}
+
+type securityRequirementsByKey struct {
+	requirements []map[string][]string
+	keys         []string
+}
+
+func (s securityRequirementsByKey) Len() int           { return len(s.keys) }
+func (s securityRequirementsByKey) Less(i, j int) bool { return s.keys[i] < s.keys[j] }
+func (s securityRequirementsByKey) Swap(i, j int) {
+	s.keys[i], s.keys[j] = s.keys[j], s.keys[i]
+	s.requirements[i], s.requirements[j] = s.requirements[j], s.requirements[i]
+}
+
+var renameSuffix = regexp.MustCompile(`^(.+)_v([0-9]+)$`)
+
+// collapseRenameSuffixes renames definitions as described by CanonicalOptions.CollapseRenameSuffixes.
+func collapseRenameSuffixes(sp *spec.Swagger) (*spec.Swagger, error) {
+	renames := map[string]string{}
+	smallest := map[string]int{}
+	for name := range sp.Definitions {
+		m := renameSuffix.FindStringSubmatch(name)
+		if m == nil {
+			continue
+		}
+		base := m[1]
+		if _, exists := sp.Definitions[base]; exists {
+			continue
+		}
+		n, err := strconv.Atoi(m[2])
+		if err != nil {
+			continue
+		}
+		if other, found := smallest[base]; !found || n < other {
+			smallest[base] = n
+			renames[base] = name
+		}
+	}
+	if len(renames) == 0 {
+		return sp, nil
+	}
+	// renames maps base names to the definition being renamed, RenameDefinitions needs the inverse.
+	inverse := make(map[string]string, len(renames))
+	for base, name := range renames {
+		inverse[name] = base
+	}
+	// Renaming Foo_v2 to Foo might make Foo_v2_v2 collapsible, so repeat until nothing changes.
+	ret, err := RenameDefinitions(inverse)(sp)
+	if err != nil {
+		return nil, err
+	}
+	return collapseRenameSuffixes(ret)
+}
@@ -0,0 +1,229 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aggregator
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"github.com/go-openapi/spec"
+	"github.com/stretchr/testify/assert"
+)
+
+// randomSpecGenerator generates random specs. The content of the spec is chosen by content, the way it is
+// represented, e.g. list orders, duplicates and nil versus empty collections, by representation. Specs generated
+// with the same content seed are semantically equal.
+type randomSpecGenerator struct {
+	content, representation *rand.Rand
+}
+
+// subset returns a random subset of the values, in order.
+func (g *randomSpecGenerator) subset(values ...string) []string {
+	var ret []string
+	for _, v := range values {
+		if g.content.Intn(2) == 0 {
+			ret = append(ret, v)
+		}
+	}
+	return ret
+}
+
+// list returns a random subset of the values in order, possibly followed by duplicates.
+func (g *randomSpecGenerator) list(values ...string) []string {
+	ret := g.subset(values...)
+	if len(ret) > 0 && g.representation.Intn(2) == 0 {
+		ret = append(ret, ret[g.representation.Intn(len(ret))])
+	}
+	if len(ret) == 0 && g.representation.Intn(2) == 0 {
+		return []string{}
+	}
+	return ret
+}
+
+// set returns a random subset of the values, possibly with duplicates, in random order.
+func (g *randomSpecGenerator) set(values ...string) []string {
+	ret := g.subset(values...)
+	if len(ret) > 0 && g.representation.Intn(2) == 0 {
+		ret = append(ret, ret[g.representation.Intn(len(ret))])
+	}
+	g.representation.Shuffle(len(ret), func(i, j int) { ret[i], ret[j] = ret[j], ret[i] })
+	if len(ret) == 0 && g.representation.Intn(2) == 0 {
+		return []string{}
+	}
+	return ret
+}
+
+func (g *randomSpecGenerator) extensions() spec.Extensions {
+	e := spec.Extensions{}
+	if g.content.Intn(2) == 0 {
+		if g.representation.Intn(2) == 0 {
+			e["x-count"] = 3
+		} else {
+			e["x-count"] = float64(3)
+		}
+	}
+	if len(e) == 0 && g.representation.Intn(2) == 0 {
+		return nil
+	}
+	return e
+}
+
+func (g *randomSpecGenerator) schema(depth int) spec.Schema {
+	s := spec.Schema{}
+	s.Type = g.set("object", "null")
+	s.Extensions = g.extensions()
+	if g.representation.Intn(2) == 0 {
+		s.Properties = map[string]spec.Schema{}
+	}
+	if depth > 0 {
+		for _, name := range g.subset("a", "b", "c") {
+			if s.Properties == nil {
+				s.Properties = map[string]spec.Schema{}
+			}
+			s.Properties[name] = g.schema(depth - 1)
+		}
+	}
+	s.Required = g.set(sortedMapKeys(s.Properties)...)
+	return s
+}
+
+func (g *randomSpecGenerator) security() []map[string][]string {
+	var ret []map[string][]string
+	for _, name := range g.subset("oauth", "basic") {
+		ret = append(ret, map[string][]string{name: g.set("read", "write")})
+	}
+	if len(ret) > 0 && g.representation.Intn(2) == 0 {
+		ret = append(ret, ret[g.representation.Intn(len(ret))])
+	}
+	g.representation.Shuffle(len(ret), func(i, j int) { ret[i], ret[j] = ret[j], ret[i] })
+	return ret
+}
+
+func (g *randomSpecGenerator) spec() *spec.Swagger {
+	sp := &spec.Swagger{SwaggerProps: spec.SwaggerProps{
+		Swagger:  "2.0",
+		Paths:    &spec.Paths{Paths: map[string]spec.PathItem{}},
+		Consumes: g.set("application/json", "application/yaml"),
+		Security: g.security(),
+	}}
+	if g.representation.Intn(2) == 0 {
+		sp.Definitions = spec.Definitions{}
+		sp.Parameters = map[string]spec.Parameter{}
+	}
+	for _, name := range g.subset("Foo", "Bar", "Baz") {
+		if sp.Definitions == nil {
+			sp.Definitions = spec.Definitions{}
+		}
+		sp.Definitions[name] = g.schema(2)
+	}
+	for _, path := range g.subset("/a", "/b") {
+		op := &spec.Operation{}
+		op.Tags = g.list("x", "y", "z")
+		op.Produces = g.set("application/json", "application/yaml")
+		op.Extensions = g.extensions()
+		for _, name := range g.subset("pretty", "watch", "limit") {
+			op.Parameters = append(op.Parameters, spec.Parameter{ParamProps: spec.ParamProps{Name: name, In: "query"}})
+		}
+		if len(op.Parameters) > 0 && g.representation.Intn(2) == 0 {
+			op.Parameters = append(op.Parameters, op.Parameters[g.representation.Intn(len(op.Parameters))])
+		}
+		g.representation.Shuffle(len(op.Parameters), func(i, j int) { op.Parameters[i], op.Parameters[j] = op.Parameters[j], op.Parameters[i] })
+		if g.content.Intn(2) == 0 {
+			op.Security = g.security()
+		} else if g.representation.Intn(2) == 0 {
+			// Explicitly the default requirements.
+			op.Security = append([]map[string][]string(nil), sp.Security...)
+		}
+		op.Responses = &spec.Responses{ResponsesProps: spec.ResponsesProps{StatusCodeResponses: map[int]spec.Response{
+			200: {ResponseProps: spec.ResponseProps{Description: "OK", Schema: &spec.Schema{SchemaProps: spec.SchemaProps{Ref: spec.MustCreateRef("#/definitions/Foo")}}}},
+		}}}
+		sp.Paths.Paths[path] = spec.PathItem{PathItemProps: spec.PathItemProps{Get: op}}
+	}
+	var tags []spec.Tag
+	for _, name := range g.set("x", "y", "z") {
+		tags = append(tags, spec.Tag{TagProps: spec.TagProps{Name: name}})
+	}
+	sp.Tags = tags
+	return sp
+}
+
+func canonicalJSON(t *testing.T, sp *spec.Swagger, opts CanonicalOptions) string {
+	canonical, err := Canonicalize(opts)(sp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bytes, err := json.Marshal(canonical)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(bytes)
+}
+
+func TestCanonicalizeProperties(t *testing.T) {
+	for seed := int64(0); seed < 200; seed++ {
+		generate := func(representationSeed int64) *spec.Swagger {
+			g := &randomSpecGenerator{content: rand.New(rand.NewSource(seed)), representation: rand.New(rand.NewSource(representationSeed))}
+			return g.spec()
+		}
+		a, b := generate(seed), generate(seed+1000)
+		origA, _ := json.Marshal(a)
+
+		for _, opts := range []CanonicalOptions{{}, {CollapseRenameSuffixes: true}} {
+			canonicalA := canonicalJSON(t, a, opts)
+			// Canonicalize does not mutate its input.
+			actualA, _ := json.Marshal(a)
+			assert.Equal(t, string(origA), string(actualA), "seed %d", seed)
+
+			// Canonicalize is idempotent.
+			var canonical *spec.Swagger
+			if err := json.Unmarshal([]byte(canonicalA), &canonical); err != nil {
+				t.Fatal(err)
+			}
+			assert.Equal(t, canonicalA, canonicalJSON(t, canonical, opts), "seed %d", seed)
+
+			// Equal specs have the same canonical form.
+			assert.Equal(t, canonicalA, canonicalJSON(t, b, opts), "seed %d", seed)
+		}
+	}
+}
+
+func TestCanonicalizeCollapseRenameSuffixes(t *testing.T) {
+	sp := &spec.Swagger{SwaggerProps: spec.SwaggerProps{
+		Paths: &spec.Paths{Paths: map[string]spec.PathItem{}},
+		Definitions: spec.Definitions{
+			"Foo_v2":    {SchemaProps: spec.SchemaProps{Type: []string{"string"}}},
+			"Foo_v3":    {SchemaProps: spec.SchemaProps{Type: []string{"integer"}}},
+			"Foo_v2_v2": {SchemaProps: spec.SchemaProps{Type: []string{"boolean"}}},
+			"Bar":       {SchemaProps: spec.SchemaProps{Ref: spec.MustCreateRef("#/definitions/Foo_v3")}},
+			"Bar_v2":    {SchemaProps: spec.SchemaProps{Type: []string{"string"}}},
+		},
+	}}
+	for i := 0; i < 5; i++ {
+		sp.Paths.Paths[fmt.Sprintf("/%d", i)] = spec.PathItem{}
+	}
+	canonical, err := Canonicalize(CanonicalOptions{CollapseRenameSuffixes: true})(sp)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, []string{"Bar", "Bar_v2", "Foo", "Foo_v2", "Foo_v3"}, keys(canonical.Definitions))
+	assert.Equal(t, spec.StringOrArray{"string"}, canonical.Definitions["Foo"].Type)
+	assert.Equal(t, spec.StringOrArray{"boolean"}, canonical.Definitions["Foo_v2"].Type)
+	bar := canonical.Definitions["Bar"]
+	assert.Equal(t, "#/definitions/Foo_v3", bar.Ref.String())
+}
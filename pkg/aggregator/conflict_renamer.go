@@ -0,0 +1,124 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aggregator
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+
+	"github.com/go-openapi/spec"
+)
+
+// Conflict describes an object of the source spec that conflicts with a different object of the same name
+// in the destination spec of a merge.
+type Conflict struct {
+	Kind ObjectKind
+	// Name is the name of the object in both specs.
+	Name string
+	// Source is MergeOptions.SourceName.
+	Source string
+	// Object is the object of the source spec, e.g. a spec.Schema for definitions. It must not be mutated.
+	Object interface{}
+}
+
+// ConflictRenamer chooses new names for conflicting objects during merges.
+type ConflictRenamer interface {
+	// RenameConflict returns a candidate name for the conflicting object. If the candidate is taken by a
+	// different object, RenameConflict is called again with the next attempt, starting at 0. If it is
+	// taken by an identical object, e.g. from an earlier merge, that object is reused.
+	RenameConflict(c Conflict, attempt int) string
+}
+
+// ConflictRenamerFunc is a ConflictRenamer implemented by a function.
+type ConflictRenamerFunc func(c Conflict, attempt int) string
+
+// RenameConflict calls f.
+func (f ConflictRenamerFunc) RenameConflict(c Conflict, attempt int) string {
+	return f(c, attempt)
+}
+
+// VersionSuffixRenamer returns the default ConflictRenamer, appending _v2, _v3 and so on. The chosen
+// name depends on the order of merges.
+func VersionSuffixRenamer() ConflictRenamer {
+	return ConflictRenamerFunc(func(c Conflict, attempt int) string {
+		return fmt.Sprintf("%s_v%d", c.Name, attempt+2)
+	})
+}
+
+// ContentHashRenamer returns a ConflictRenamer appending a hash of the object's content, so that the same
+// conflicting object always gets the same name, independent of the order of the later merges. Only
+// conflicting objects are renamed, so the object of the destination, or of the first source merged into it,
+// keeps the plain name, and which variant that is does depend on the merge order. Schemas are hashed in their
+// CanonicalSchema form, so equivalent schemas get the same name as well. On the unlikely hash collision, a
+// _v2, _v3 and so on suffix is appended as well.
+func ContentHashRenamer() ConflictRenamer {
+	return ConflictRenamerFunc(func(c Conflict, attempt int) string {
+		object, err := canonicalObject(c.Object)
+		var bytes []byte
+		if err == nil {
+			bytes, err = json.Marshal(object)
+		}
+		if err != nil {
+			// Fall back to the version suffix for objects that cannot be serialized.
+			return VersionSuffixRenamer().RenameConflict(c, attempt)
+		}
+		sum := sha256.Sum256(bytes)
+		name := fmt.Sprintf("%s_%s", c.Name, hex.EncodeToString(sum[:4]))
+		if attempt > 0 {
+			name = fmt.Sprintf("%s_v%d", name, attempt+1)
+		}
+		return name
+	})
+}
+
+// canonicalObject returns a copy of the object with its schema, if any, in CanonicalSchema form.
+func canonicalObject(object interface{}) (interface{}, error) {
+	var err error
+	switch o := object.(type) {
+	case spec.Schema:
+		return CanonicalSchema(&o, false)
+	case spec.Parameter:
+		if o.Schema != nil {
+			o.Schema, err = CanonicalSchema(o.Schema, false)
+		}
+		return o, err
+	case spec.Response:
+		if o.Schema != nil {
+			o.Schema, err = CanonicalSchema(o.Schema, false)
+		}
+		return o, err
+	}
+	return object, nil
+}
+
+// SourcePrefixRenamer returns a ConflictRenamer prefixing the name with the source name and a dot, e.g.
+// "metrics.Status" for the Status definition of the "metrics" source. A _v2, _v3 and so on suffix is appended
+// if that name is taken as well. Without source name, it behaves like VersionSuffixRenamer.
+func SourcePrefixRenamer() ConflictRenamer {
+	return ConflictRenamerFunc(func(c Conflict, attempt int) string {
+		if c.Source == "" {
+			return VersionSuffixRenamer().RenameConflict(c, attempt)
+		}
+		name := c.Source + "." + c.Name
+		if attempt > 0 {
+			name = fmt.Sprintf("%s_v%d", name, attempt+1)
+		}
+		return name
+	})
+}
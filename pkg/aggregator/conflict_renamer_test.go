@@ -0,0 +1,176 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aggregator
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/ghodss/yaml"
+	"github.com/go-openapi/spec"
+	"github.com/stretchr/testify/assert"
+)
+
+func conflictingSpec(path, fooType string) *spec.Swagger {
+	var s *spec.Swagger
+	yaml.Unmarshal([]byte(fmt.Sprintf(`
+swagger: "2.0"
+paths:
+  %s:
+    get:
+      responses:
+        200:
+          description: "OK"
+          schema:
+            $ref: "#/definitions/Foo"
+definitions:
+  Foo:
+    type: "%s"
+`, path, fooType)), &s)
+	return s
+}
+
+func TestContentHashRenamerIsOrderIndependent(t *testing.T) {
+	ast := assert.New(t)
+	merge := func(types ...string) *spec.Swagger {
+		dest := conflictingSpec("/base", "string")
+		for i, typ := range types {
+			_, err := MergeSpecsWithOptions(dest, conflictingSpec(fmt.Sprintf("/%s%d", typ, i), typ), MergeOptions{ConflictRenamer: ContentHashRenamer()})
+			ast.NoError(err)
+		}
+		return dest
+	}
+
+	a := merge("integer", "boolean")
+	b := merge("boolean", "integer")
+	ast.Equal(keys(a.Definitions), keys(b.Definitions))
+	ast.Len(a.Definitions, 3)
+	for name, def := range a.Definitions {
+		ast.Equal(def, b.Definitions[name], name)
+	}
+
+	// Merging the same conflicting definition again reuses the renamed definition.
+	report, err := MergeSpecsWithOptions(a, conflictingSpec("/again", "integer"), MergeOptions{ConflictRenamer: ContentHashRenamer()})
+	ast.NoError(err)
+	ast.Len(a.Definitions, 3)
+	if ast.Len(report.Renamed, 1) {
+		ast.Equal(RenameReasonReused, report.Renamed[0].Reason)
+		ast.Contains(a.Definitions, report.Renamed[0].NewName)
+	}
+}
+
+func TestContentHashRenamerSourceOrder(t *testing.T) {
+	ast := assert.New(t)
+	hashName := func(typ string) string {
+		return ContentHashRenamer().RenameConflict(Conflict{
+			Kind:   DefinitionKind,
+			Name:   "Foo",
+			Object: spec.Schema{SchemaProps: spec.SchemaProps{Type: []string{typ}}},
+		}, 0)
+	}
+	for _, types := range [][]string{
+		{"string", "integer", "boolean"},
+		{"string", "boolean", "integer"},
+		{"integer", "string", "boolean"},
+		{"integer", "boolean", "string"},
+		{"boolean", "string", "integer"},
+		{"boolean", "integer", "string"},
+	} {
+		// The first source is the destination and keeps the plain name, all others are named by their content.
+		dest := conflictingSpec("/"+types[0], types[0])
+		for _, typ := range types[1:] {
+			_, err := MergeSpecsWithOptions(dest, conflictingSpec("/"+typ, typ), MergeOptions{ConflictRenamer: ContentHashRenamer()})
+			ast.NoError(err)
+		}
+		expected := map[string]string{"Foo": types[0]}
+		for _, typ := range types[1:] {
+			expected[hashName(typ)] = typ
+		}
+		actual := map[string]string{}
+		for name, def := range dest.Definitions {
+			actual[name] = def.Type[0]
+		}
+		ast.Equal(expected, actual, "order %v", types)
+		for _, typ := range types {
+			name := dest.Paths.Paths["/"+typ].Get.Responses.StatusCodeResponses[200].Schema.Ref.String()
+			ast.Equal(typ, dest.Definitions[name[len("#/definitions/"):]].Type[0], "order %v", types)
+		}
+	}
+}
+
+func TestContentHashRenamerHashesCanonicalSchemas(t *testing.T) {
+	ast := assert.New(t)
+	merge := func(required ...string) string {
+		dest := conflictingSpec("/a", "string")
+		source := conflictingSpec("/b", "object")
+		foo := source.Definitions["Foo"]
+		foo.Required = required
+		source.Definitions["Foo"] = foo
+		_, err := MergeSpecsWithOptions(dest, source, MergeOptions{ConflictRenamer: ContentHashRenamer()})
+		ast.NoError(err)
+		return dest.Paths.Paths["/b"].Get.Responses.StatusCodeResponses[200].Schema.Ref.String()
+	}
+	ast.Equal(merge("a", "b"), merge("b", "a", "a"))
+	ast.NotEqual(merge("a", "b"), merge("a"))
+}
+
+func TestSourcePrefixRenamer(t *testing.T) {
+	ast := assert.New(t)
+	dest := conflictingSpec("/a", "string")
+	report, err := MergeSpecsWithOptions(dest, conflictingSpec("/b", "integer"), MergeOptions{SourceName: "metrics", ConflictRenamer: SourcePrefixRenamer()})
+	if !ast.NoError(err) {
+		return
+	}
+	ast.Equal([]MergedObject{{Kind: DefinitionKind, Name: "Foo", NewName: "metrics.Foo", Reason: RenameReasonConflict}}, report.Renamed)
+	ast.Equal("#/definitions/metrics.Foo", dest.Paths.Paths["/b"].Get.Responses.StatusCodeResponses[200].Schema.Ref.String())
+
+	// A different definition from the same source gets a version suffix.
+	_, err = MergeSpecsWithOptions(dest, conflictingSpec("/c", "boolean"), MergeOptions{SourceName: "metrics", ConflictRenamer: SourcePrefixRenamer()})
+	ast.NoError(err)
+	ast.Equal("#/definitions/metrics.Foo_v2", dest.Paths.Paths["/c"].Get.Responses.StatusCodeResponses[200].Schema.Ref.String())
+}
+
+func TestConflictRenamerFunc(t *testing.T) {
+	ast := assert.New(t)
+	var conflicts []Conflict
+	renamer := ConflictRenamerFunc(func(c Conflict, attempt int) string {
+		conflicts = append(conflicts, c)
+		if attempt == 0 {
+			// Taken by a different definition, so another attempt is made.
+			return "Bar"
+		}
+		return "Custom" + c.Name
+	})
+	dest := conflictingSpec("/a", "string")
+	dest.Definitions["Bar"] = spec.Schema{SchemaProps: spec.SchemaProps{Type: []string{"object"}}}
+	_, err := MergeSpecsWithOptions(dest, conflictingSpec("/b", "integer"), MergeOptions{SourceName: "src", ConflictRenamer: renamer})
+	if !ast.NoError(err) {
+		return
+	}
+	ast.Contains(dest.Definitions, "CustomFoo")
+	if ast.Len(conflicts, 2) {
+		ast.Equal(DefinitionKind, conflicts[0].Kind)
+		ast.Equal("Foo", conflicts[0].Name)
+		ast.Equal("src", conflicts[0].Source)
+		ast.Equal(spec.StringOrArray{"integer"}, conflicts[0].Object.(spec.Schema).Type)
+	}
+
+	_, err = MergeSpecsWithOptions(dest, conflictingSpec("/c", "boolean"), MergeOptions{
+		ConflictRenamer: ConflictRenamerFunc(func(c Conflict, attempt int) string { return "Bar" }),
+	})
+	ast.Error(err)
+}
@@ -0,0 +1,81 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aggregator
+
+import (
+	"reflect"
+
+	"github.com/go-openapi/spec"
+)
+
+// DefinitionEquivalence returns true if two definitions describe the same model. It must not mutate
+// its arguments.
+type DefinitionEquivalence func(a, b *spec.Schema) bool
+
+var defaultDefinitionEquivalence = ExactEquivalence()
+
+// ExactEquivalence returns a DefinitionEquivalence that only considers deeply equal definitions the same.
+func ExactEquivalence() DefinitionEquivalence {
+	return func(a, b *spec.Schema) bool {
+		return reflect.DeepEqual(a, b)
+	}
+}
+
+// CanonicalEquivalence returns a DefinitionEquivalence comparing the canonical forms of the definitions (see
+// CanonicalSchema). With ignoreDocumentation, definitions that only differ in titles, descriptions, examples
+// and external documentation are the same as well.
+func CanonicalEquivalence(ignoreDocumentation bool) DefinitionEquivalence {
+	return func(a, b *spec.Schema) bool {
+		if reflect.DeepEqual(a, b) {
+			return true
+		}
+		canonicalA, err := CanonicalSchema(a, ignoreDocumentation)
+		if err != nil {
+			return false
+		}
+		canonicalB, err := CanonicalSchema(b, ignoreDocumentation)
+		if err != nil {
+			return false
+		}
+		return reflect.DeepEqual(canonicalA, canonicalB)
+	}
+}
+
+// CanonicalSchema returns a copy of the schema in which equivalent schemas are deeply equal: empty lists and
+// maps are nil, extension values have the types JSON decoding gives them, and required properties and types
+// are sorted and deduplicated. With ignoreDocumentation, titles, descriptions, examples and external
+// documentation are removed from the schema and all its sub-schemas. The input is not mutated.
+func CanonicalSchema(s *spec.Schema, ignoreDocumentation bool) (*spec.Schema, error) {
+	// The JSON round trip of the copy drops empty lists and maps, and normalizes extension values.
+	ret, err := deepCopySchema(s)
+	if err != nil {
+		return nil, err
+	}
+	(&specWalker{SpecVisitor: &SpecVisitor{
+		Schema: func(_ string, s *spec.Schema) {
+			s.Required = sortedUnique(s.Required)
+			s.Type = spec.StringOrArray(sortedUnique(s.Type))
+			if ignoreDocumentation {
+				s.Title = ""
+				s.Description = ""
+				s.Example = nil
+				s.ExternalDocs = nil
+			}
+		},
+	}, rewrite: true}).walkSchema("", ret)
+	return ret, nil
+}
@@ -0,0 +1,198 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aggregator
+
+import (
+	"testing"
+
+	"github.com/ghodss/yaml"
+	"github.com/go-openapi/spec"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCanonicalEquivalence(t *testing.T) {
+	base := spec.Schema{
+		SchemaProps: spec.SchemaProps{
+			Description: "A foo.",
+			Type:        []string{"object"},
+			Required:    []string{"a", "b"},
+			Properties: map[string]spec.Schema{
+				"a": {SchemaProps: spec.SchemaProps{Type: []string{"string"}, Description: "The a."}},
+				"b": {SchemaProps: spec.SchemaProps{Type: []string{"integer"}}},
+			},
+		},
+		VendorExtensible: spec.VendorExtensible{Extensions: spec.Extensions{"x-count": 1}},
+	}
+	modify := func(f func(s *spec.Schema)) *spec.Schema {
+		s, _ := CanonicalSchema(&base, false)
+		f(s)
+		return s
+	}
+
+	tcs := []struct {
+		name         string
+		other        *spec.Schema
+		canonical    bool
+		ignoringDocs bool
+	}{
+		{"identical", modify(func(s *spec.Schema) {}), true, true},
+		{"required order", modify(func(s *spec.Schema) { s.Required = []string{"b", "a", "b"} }), true, true},
+		{"empty lists", modify(func(s *spec.Schema) { s.Enum = []interface{}{}; s.AllOf = []spec.Schema{} }), true, true},
+		{"extension value type", modify(func(s *spec.Schema) { s.Extensions["x-count"] = float64(1) }), true, true},
+		{"description", modify(func(s *spec.Schema) { s.Description = "Another foo." }), false, true},
+		{"property title", modify(func(s *spec.Schema) {
+			a := s.Properties["a"]
+			a.Title = "A"
+			s.Properties["a"] = a
+		}), false, true},
+		{"property type", modify(func(s *spec.Schema) {
+			a := s.Properties["a"]
+			a.Type = []string{"boolean"}
+			s.Properties["a"] = a
+		}), false, false},
+		{"required", modify(func(s *spec.Schema) { s.Required = []string{"a"} }), false, false},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			before, _ := CanonicalSchema(&base, false)
+			assert.Equal(t, tc.canonical, CanonicalEquivalence(false)(&base, tc.other))
+			assert.Equal(t, tc.ignoringDocs, CanonicalEquivalence(true)(&base, tc.other))
+			assert.Equal(t, tc.canonical, CanonicalEquivalence(false)(tc.other, &base))
+			after, _ := CanonicalSchema(&base, false)
+			assert.Equal(t, before, after)
+		})
+	}
+	assert.False(t, ExactEquivalence()(&base, modify(func(s *spec.Schema) { s.Required = []string{"b", "a"} })))
+}
+
+func TestMergeSpecsDefinitionEquivalence(t *testing.T) {
+	var dest, source *spec.Swagger
+	yaml.Unmarshal([]byte(`
+swagger: "2.0"
+paths:
+  /a:
+    get:
+      responses:
+        200:
+          description: "OK"
+          schema:
+            $ref: "#/definitions/Foo"
+definitions:
+  Foo:
+    description: "Foo from a."
+    type: "object"
+    required: ["x", "y"]
+    properties:
+      x:
+        type: "string"
+      y:
+        type: "string"
+`), &dest)
+	yaml.Unmarshal([]byte(`
+swagger: "2.0"
+paths:
+  /b:
+    get:
+      responses:
+        200:
+          description: "OK"
+          schema:
+            $ref: "#/definitions/Foo"
+definitions:
+  Foo:
+    description: "Foo from b."
+    type: "object"
+    required: ["y", "x"]
+    properties:
+      x:
+        type: "string"
+      y:
+        type: "string"
+`), &source)
+
+	ast := assert.New(t)
+	for _, tc := range []struct {
+		equivalence DefinitionEquivalence
+		renamed     bool
+	}{
+		{nil, true},
+		{ExactEquivalence(), true},
+		{CanonicalEquivalence(true), false},
+	} {
+		merged, _ := cloneSpec(dest)
+		report, err := MergeSpecsWithOptions(merged, source, MergeOptions{DefinitionEquivalence: tc.equivalence})
+		if !ast.NoError(err) {
+			continue
+		}
+		ast.Equal(tc.renamed, len(report.Renamed) == 1)
+		if !tc.renamed {
+			ast.Equal([]string{"Foo"}, keys(merged.Definitions))
+			ast.Equal("Foo from a.", merged.Definitions["Foo"].Description)
+			ast.Equal("#/definitions/Foo", merged.Paths.Paths["/b"].Get.Responses.StatusCodeResponses[200].Schema.Ref.String())
+		}
+	}
+}
+
+func TestMergeSpecsDefaultDefinitionEquivalence(t *testing.T) {
+	var dest, source *spec.Swagger
+	yaml.Unmarshal([]byte(`
+swagger: "2.0"
+paths:
+  /a:
+    get:
+      responses:
+        200:
+          description: "OK"
+          schema:
+            $ref: "#/definitions/Foo"
+definitions:
+  Foo:
+    type: "object"
+    required: ["x", "y"]
+`), &dest)
+	yaml.Unmarshal([]byte(`
+swagger: "2.0"
+paths:
+  /b:
+    get:
+      responses:
+        200:
+          description: "OK"
+          schema:
+            $ref: "#/definitions/Foo"
+definitions:
+  Foo:
+    type: "object"
+    required: ["y", "x"]
+`), &source)
+
+	ast := assert.New(t)
+	// MergeSpecs and the default options only reuse deeply equal definitions.
+	merged, _ := cloneSpec(dest)
+	if ast.NoError(MergeSpecs(merged, source)) {
+		ast.Equal([]string{"Foo", "Foo_v2"}, keys(merged.Definitions))
+	}
+	merged, _ = cloneSpec(dest)
+	ast.Error(MergeSpecsFailOnDefinitionConflict(merged, source))
+
+	merged, _ = cloneSpec(dest)
+	report, err := MergeSpecsWithOptions(merged, source, MergeOptions{DefinitionEquivalence: CanonicalEquivalence(false)})
+	if ast.NoError(err) {
+		ast.Empty(report.Renamed)
+		ast.Equal([]string{"Foo"}, keys(merged.Definitions))
+	}
+}
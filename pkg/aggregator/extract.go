@@ -0,0 +1,208 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aggregator
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/go-openapi/spec"
+)
+
+// ExtractOptions configures ExtractDefinitions.
+type ExtractOptions struct {
+	// IncludePaths keeps the operations using any of the extracted definitions, directly or through other
+	// definitions, shared parameters or shared responses, together with everything they use. Security
+	// definitions and tags no kept operation uses are removed, and so are the global security requirements
+	// if every kept operation has its own.
+	IncludePaths bool
+}
+
+// ExtractDefinitions returns a minimal self-contained spec with the named definitions and all definitions they
+// reference, transitively. Without ExtractOptions.IncludePaths, the returned spec only has the swagger version,
+// the info and the definitions. Unknown definition names are an error.
+// It does not modify the input, but the output shares data structures with the input.
+func ExtractDefinitions(sp *spec.Swagger, names []string, opts ExtractOptions) (*spec.Swagger, error) {
+	for _, name := range names {
+		if _, found := sp.Definitions[name]; !found {
+			return nil, fmt.Errorf("definition %q not found", name)
+		}
+	}
+	closure := definitionClosure(sp, names)
+
+	ret := &spec.Swagger{SwaggerProps: spec.SwaggerProps{Swagger: sp.Swagger, Info: sp.Info}}
+	if opts.IncludePaths {
+		users := definitionUsers(sp, names)
+		filtered := *FilterSpecByOperations(sp, func(path, _ string, op *spec.Operation) bool {
+			pathItem := sp.Paths.Paths[path]
+			return operationReferencesAny(sp, &pathItem, op, users)
+		})
+		pruneSecurityAndTags(&filtered)
+		ret = &filtered
+	}
+
+	used := usedReferencesForSpec(ret)
+	ret.Definitions = make(spec.Definitions, len(closure)+len(used.definitions))
+	for name, def := range sp.Definitions {
+		if closure[name] || used.definitions[name] {
+			ret.Definitions[name] = def
+		}
+	}
+	ret.Parameters, ret.Responses = nil, nil
+	for name, param := range sp.Parameters {
+		if used.parameters[name] {
+			if ret.Parameters == nil {
+				ret.Parameters = map[string]spec.Parameter{}
+			}
+			ret.Parameters[name] = param
+		}
+	}
+	for name, resp := range sp.Responses {
+		if used.responses[name] {
+			if ret.Responses == nil {
+				ret.Responses = map[string]spec.Response{}
+			}
+			ret.Responses[name] = resp
+		}
+	}
+	return ret, nil
+}
+
+// definitionClosure returns the named definitions and all definitions reachable from them.
+func definitionClosure(sp *spec.Swagger, names []string) map[string]bool {
+	closure := map[string]bool{}
+	walkOnReferencesFrom(func(ref *spec.Ref) {
+		if refStr := ref.String(); strings.HasPrefix(refStr, definitionPrefix) {
+			if _, found := sp.Definitions[refStr[len(definitionPrefix):]]; found {
+				closure[refStr[len(definitionPrefix):]] = true
+			}
+		}
+	}, sp, func(walker *readonlyReferenceWalker) {
+		for _, name := range names {
+			ref := spec.MustCreateRef(definitionPrefix + name)
+			walker.walkRefCallback(&ref)
+		}
+	})
+	return closure
+}
+
+// definitionUsers returns the references of the named definitions and of all definitions, shared parameters and
+// shared responses from which any of them is reachable, e.g. "#/definitions/PodList" for "Pod".
+func definitionUsers(sp *spec.Swagger, names []string) map[string]bool {
+	referrers := map[string][]string{}
+	walkFrom := func(from string) *readonlyReferenceWalker {
+		return &readonlyReferenceWalker{root: sp, walkRefCallback: func(ref *spec.Ref) {
+			if refStr := ref.String(); refStr != "" {
+				referrers[refStr] = append(referrers[refStr], from)
+			}
+		}}
+	}
+	for name, def := range sp.Definitions {
+		walkFrom(definitionPrefix + name).walkSchema(&def)
+	}
+	for name, param := range sp.Parameters {
+		walkFrom(parameterPrefix + name).walkParams([]spec.Parameter{param})
+	}
+	for name, resp := range sp.Responses {
+		walkFrom(responsePrefix + name).walkResponse(&resp)
+	}
+
+	users := map[string]bool{}
+	var queue []string
+	for _, name := range names {
+		queue = append(queue, definitionPrefix+name)
+	}
+	for len(queue) > 0 {
+		refStr := queue[0]
+		queue = queue[1:]
+		if users[refStr] {
+			continue
+		}
+		users[refStr] = true
+		queue = append(queue, referrers[refStr]...)
+	}
+	return users
+}
+
+// operationReferencesAny returns true if the operation or the path-level parameters of its path item directly
+// reference any of the given references. References are not followed.
+func operationReferencesAny(sp *spec.Swagger, pathItem *spec.PathItem, op *spec.Operation, refs map[string]bool) bool {
+	found := false
+	walker := &readonlyReferenceWalker{root: sp, walkRefCallback: func(ref *spec.Ref) {
+		if refs[ref.String()] {
+			found = true
+		}
+	}}
+	walker.walkParams(pathItem.Parameters)
+	walker.walkOperation(op)
+	return found
+}
+
+// pruneSecurityAndTags removes the security definitions and tags not used by any operation of the spec, and the
+// global security requirements if every operation has its own. It replaces rather than modifies the security
+// definitions and tags of the spec, which may be shared with another spec.
+func pruneSecurityAndTags(sp *spec.Swagger) {
+	usedSecurity, usedTags := map[string]bool{}, map[string]bool{}
+	inheritsSecurity := false
+	addSecurity := func(requirements []map[string][]string) {
+		for _, requirement := range requirements {
+			for name := range requirement {
+				usedSecurity[name] = true
+			}
+		}
+	}
+	if sp.Paths != nil {
+		for _, pathItem := range sp.Paths.Paths {
+			for _, m := range pathItemOperations(&pathItem) {
+				op := *m.op
+				if op == nil {
+					continue
+				}
+				if op.Security == nil {
+					inheritsSecurity = true
+				}
+				addSecurity(op.Security)
+				for _, tag := range op.Tags {
+					usedTags[tag] = true
+				}
+			}
+		}
+	}
+	if inheritsSecurity {
+		addSecurity(sp.Security)
+	} else {
+		sp.Security = nil
+	}
+
+	var securityDefinitions spec.SecurityDefinitions
+	for name, def := range sp.SecurityDefinitions {
+		if usedSecurity[name] {
+			if securityDefinitions == nil {
+				securityDefinitions = spec.SecurityDefinitions{}
+			}
+			securityDefinitions[name] = def
+		}
+	}
+	sp.SecurityDefinitions = securityDefinitions
+	var tags []spec.Tag
+	for _, tag := range sp.Tags {
+		if usedTags[tag.Name] {
+			tags = append(tags, tag)
+		}
+	}
+	sp.Tags = tags
+}
@@ -0,0 +1,186 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aggregator
+
+import (
+	"testing"
+
+	"github.com/ghodss/yaml"
+	"github.com/go-openapi/spec"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExtractDefinitions(t *testing.T) {
+	var sp *spec.Swagger
+	yaml.Unmarshal([]byte(`
+swagger: "2.0"
+info:
+  title: "Kubernetes"
+security:
+- basic: []
+securityDefinitions:
+  basic:
+    type: "basic"
+  oauth:
+    type: "oauth2"
+    flow: "implicit"
+    authorizationUrl: "https://example.com/auth"
+  apiKey:
+    type: "apiKey"
+    name: "key"
+    in: "header"
+tags:
+- name: "pods"
+- name: "services"
+paths:
+  /api/v1/pods:
+    get:
+      tags:
+      - "pods"
+      security:
+      - oauth: ["read"]
+      parameters:
+      - $ref: "#/parameters/pretty"
+      responses:
+        200:
+          description: "OK"
+          schema:
+            $ref: "#/definitions/PodList"
+  /api/v1/pods/{name}:
+    get:
+      responses:
+        200:
+          $ref: "#/responses/Pod"
+    delete:
+      responses:
+        200:
+          description: "OK"
+          schema:
+            $ref: "#/definitions/Status"
+  /api/v1/pods/{name}/binding:
+    parameters:
+    - name: "body"
+      in: "body"
+      schema:
+        $ref: "#/definitions/Pod"
+    post:
+      responses:
+        201:
+          description: "Created"
+  /api/v1/services:
+    get:
+      tags:
+      - "services"
+      security:
+      - apiKey: []
+      responses:
+        200:
+          description: "OK"
+          schema:
+            $ref: "#/definitions/Service"
+parameters:
+  pretty:
+    name: "pretty"
+    in: "query"
+    type: "string"
+  unused:
+    name: "unused"
+    in: "query"
+    type: "string"
+responses:
+  Pod:
+    description: "OK"
+    schema:
+      $ref: "#/definitions/Pod"
+definitions:
+  PodList:
+    type: "object"
+    properties:
+      items:
+        type: "array"
+        items:
+          $ref: "#/definitions/Pod"
+  Pod:
+    type: "object"
+    properties:
+      metadata:
+        $ref: "#/definitions/ObjectMeta"
+      spec:
+        $ref: "#/definitions/PodSpec"
+  PodSpec:
+    type: "object"
+    properties:
+      template:
+        $ref: "#/definitions/Pod"
+  ObjectMeta:
+    type: "object"
+  Service:
+    type: "object"
+    properties:
+      metadata:
+        $ref: "#/definitions/ObjectMeta"
+  Status:
+    type: "object"
+`), &sp)
+	orig, _ := cloneSpec(sp)
+	ast := assert.New(t)
+
+	extracted, err := ExtractDefinitions(sp, []string{"Pod"}, ExtractOptions{})
+	if !ast.NoError(err) {
+		return
+	}
+	ast.Equal([]string{"ObjectMeta", "Pod", "PodSpec"}, keys(extracted.Definitions))
+	ast.Nil(extracted.Paths)
+	ast.Nil(extracted.Parameters)
+	ast.Equal("2.0", extracted.Swagger)
+	ast.Equal("Kubernetes", extracted.Info.Title)
+
+	extracted, err = ExtractDefinitions(sp, []string{"Pod"}, ExtractOptions{IncludePaths: true})
+	if !ast.NoError(err) {
+		return
+	}
+	ast.Equal([]string{"/api/v1/pods", "/api/v1/pods/{name}", "/api/v1/pods/{name}/binding"}, sortedMapKeys(extracted.Paths.Paths))
+	ast.Nil(extracted.Paths.Paths["/api/v1/pods/{name}"].Delete)
+	ast.Equal([]string{"ObjectMeta", "Pod", "PodList", "PodSpec"}, keys(extracted.Definitions))
+	ast.Equal([]string{"pretty"}, sortedMapKeys(extracted.Parameters))
+	ast.Equal([]string{"Pod"}, sortedMapKeys(extracted.Responses))
+	ast.Equal([]string{"basic", "oauth"}, sortedMapKeys(extracted.SecurityDefinitions))
+	ast.Equal([]map[string][]string{{"basic": {}}}, extracted.Security)
+	ast.Equal([]spec.Tag{{TagProps: spec.TagProps{Name: "pods"}}}, extracted.Tags)
+
+	extracted, err = ExtractDefinitions(sp, []string{"ObjectMeta"}, ExtractOptions{IncludePaths: true})
+	if !ast.NoError(err) {
+		return
+	}
+	ast.Equal([]string{"/api/v1/pods", "/api/v1/pods/{name}", "/api/v1/pods/{name}/binding", "/api/v1/services"}, sortedMapKeys(extracted.Paths.Paths))
+	ast.Equal([]string{"ObjectMeta", "Pod", "PodList", "PodSpec", "Service"}, keys(extracted.Definitions))
+
+	extracted, err = ExtractDefinitions(sp, []string{"Service"}, ExtractOptions{IncludePaths: true})
+	if !ast.NoError(err) {
+		return
+	}
+	ast.Equal([]string{"/api/v1/services"}, sortedMapKeys(extracted.Paths.Paths))
+	ast.Equal([]string{"apiKey"}, sortedMapKeys(extracted.SecurityDefinitions))
+	ast.Nil(extracted.Security)
+	ast.Equal([]spec.Tag{{TagProps: spec.TagProps{Name: "services"}}}, extracted.Tags)
+	ast.Nil(extracted.Parameters)
+	ast.Nil(extracted.Responses)
+
+	_, err = ExtractDefinitions(sp, []string{"Pod", "Missing"}, ExtractOptions{})
+	ast.EqualError(err, `definition "Missing" not found`)
+	ast.Equal(DebugSpec{orig}, DebugSpec{sp})
+}
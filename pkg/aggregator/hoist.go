@@ -0,0 +1,248 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aggregator
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/go-openapi/spec"
+
+	"k8s.io/kube-openapi/pkg/common"
+)
+
+// HoistInlineSchemas returns a SpecTransformer moving inline object schemas with properties into new
+// definitions, and replacing them by references. The description of an inline schema stays next to the
+// reference. Identical inline schemas, compared like CanonicalEquivalence(false) after their own inline
+// schemas are hoisted, share one definition.
+//
+// Definition names are generated from where the schema is first found, e.g. FooSpec for the spec property of
+// definition Foo, or ListFoosResponse200 for the response 200 of operation listFoos. Operations without ID are
+// named by method and path. _v2, _v3 and so on are appended to names used already.
+func HoistInlineSchemas() SpecTransformer {
+	return func(sp *spec.Swagger) (*spec.Swagger, error) {
+		h := &hoister{
+			existing: sp.Definitions,
+			hoisted:  spec.Definitions{},
+			byJSON:   map[string]string{},
+		}
+		var operationName []string
+		ret, err := RewriteSpec(sp, &SpecVisitor{
+			PathItem: func(pointer string, _ *spec.PathItem) {
+				operationName = pathTokens(pointer)
+			},
+			Operation: func(pointer string, op *spec.Operation) {
+				tokens := pathTokens(pointer)
+				if op.ID != "" {
+					operationName = []string{op.ID}
+				} else {
+					// The method goes first.
+					operationName = append(tokens[len(tokens)-1:], tokens[:len(tokens)-1]...)
+				}
+			},
+			Parameter: func(pointer string, p *spec.Parameter) {
+				if p.Schema == nil {
+					return
+				}
+				if strings.HasPrefix(pointer, "/parameters/") {
+					h.hoistRoot(p.Schema, p.Name, "Parameter")
+				} else {
+					h.hoistRoot(p.Schema, append(operationName, p.Name)...)
+				}
+			},
+			Response: func(pointer string, r *spec.Response) {
+				if r.Schema == nil {
+					return
+				}
+				tokens := strings.Split(pointer, "/")
+				last := common.UnescapeJsonPointer(tokens[len(tokens)-1])
+				if strings.HasPrefix(pointer, "/responses/") {
+					h.hoistRoot(r.Schema, last, "Response")
+				} else if last == "default" {
+					h.hoistRoot(r.Schema, append(operationName, "DefaultResponse")...)
+				} else {
+					h.hoistRoot(r.Schema, append(operationName, "Response", last)...)
+				}
+			},
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, name := range sortedMapKeys(ret.Definitions) {
+			def := ret.Definitions[name]
+			h.hoistChildren(&def, name)
+			ret.Definitions[name] = def
+		}
+		if h.err != nil {
+			return nil, h.err
+		}
+		if len(h.hoisted) > 0 && ret.Definitions == nil {
+			ret.Definitions = spec.Definitions{}
+		}
+		for name, def := range h.hoisted {
+			ret.Definitions[name] = def
+		}
+		return ret, nil
+	}
+}
+
+// hoister collects hoisted definitions.
+type hoister struct {
+	existing spec.Definitions
+	hoisted  spec.Definitions
+	// byJSON maps the canonical JSON of hoisted definitions to their name.
+	byJSON map[string]string
+	err    error
+}
+
+// hoistRoot hoists the schema, after hoisting its children, if it is an inline object schema.
+func (h *hoister) hoistRoot(s *spec.Schema, name ...string) {
+	h.hoistChildren(s, name...)
+	if !isInlineObject(s) || h.err != nil {
+		return
+	}
+	description := s.Description
+	def := *s
+	def.Description = ""
+	canonical, err := CanonicalSchema(&def, false)
+	if err != nil {
+		h.err = err
+		return
+	}
+	bytes, err := json.Marshal(canonical)
+	if err != nil {
+		h.err = err
+		return
+	}
+	defName, found := h.byJSON[string(bytes)]
+	if !found {
+		defName = h.newName(name)
+		h.byJSON[string(bytes)] = defName
+		h.hoisted[defName] = def
+	}
+	*s = spec.Schema{SchemaProps: spec.SchemaProps{
+		Ref:         spec.MustCreateRef(definitionPrefix + defName),
+		Description: description,
+	}}
+}
+
+// hoistChildren hoists the inline object schemas below the schema, deepest first.
+func (h *hoister) hoistChildren(s *spec.Schema, name ...string) {
+	child := func(tokens ...string) []string {
+		return append(append([]string(nil), name...), tokens...)
+	}
+	for _, m := range []struct {
+		token   string
+		schemas map[string]spec.Schema
+	}{
+		{"Definition", s.Definitions},
+		{"", s.Properties},
+		{"PatternProperty", s.PatternProperties},
+	} {
+		for i, k := range sortedMapKeys(m.schemas) {
+			sub := m.schemas[k]
+			if m.token == "" {
+				h.hoistRoot(&sub, child(k)...)
+			} else {
+				h.hoistRoot(&sub, child(m.token, strconv.Itoa(i))...)
+			}
+			m.schemas[k] = sub
+		}
+	}
+	for _, l := range []struct {
+		token   string
+		schemas []spec.Schema
+	}{
+		{"AllOf", s.AllOf},
+		{"AnyOf", s.AnyOf},
+		{"OneOf", s.OneOf},
+	} {
+		for i := range l.schemas {
+			h.hoistRoot(&l.schemas[i], child(l.token, strconv.Itoa(i))...)
+		}
+	}
+	if s.Not != nil {
+		h.hoistRoot(s.Not, child("Not")...)
+	}
+	if s.Items != nil {
+		if s.Items.Schema != nil {
+			h.hoistRoot(s.Items.Schema, child("Item")...)
+		}
+		for i := range s.Items.Schemas {
+			h.hoistRoot(&s.Items.Schemas[i], child("Item", strconv.Itoa(i))...)
+		}
+	}
+	if s.AdditionalProperties != nil && s.AdditionalProperties.Schema != nil {
+		h.hoistRoot(s.AdditionalProperties.Schema, child("Value")...)
+	}
+	if s.AdditionalItems != nil && s.AdditionalItems.Schema != nil {
+		h.hoistRoot(s.AdditionalItems.Schema, child("AdditionalItem")...)
+	}
+}
+
+// newName returns an unused definition name for the tokens.
+func (h *hoister) newName(tokens []string) string {
+	base := identifier(tokens)
+	name := base
+	for i := 2; ; i++ {
+		_, existing := h.existing[name]
+		_, hoisted := h.hoisted[name]
+		if !existing && !hoisted {
+			return name
+		}
+		name = fmt.Sprintf("%s_v%d", base, i)
+	}
+}
+
+// isInlineObject returns true if the schema is an object schema with properties, not a reference.
+func isInlineObject(s *spec.Schema) bool {
+	return s.Ref.String() == "" && len(s.Properties) > 0 &&
+		(len(s.Type) == 0 || len(s.Type) == 1 && s.Type[0] == "object")
+}
+
+// pathTokens returns the path and method of the pointer to a path item or operation, e.g. "/apis/foo" and "get"
+// for /paths/~1apis~1foo/get.
+func pathTokens(pointer string) []string {
+	tokens := strings.Split(strings.TrimPrefix(pointer, "/paths/"), "/")
+	for i := range tokens {
+		tokens[i] = common.UnescapeJsonPointer(tokens[i])
+	}
+	return tokens
+}
+
+// identifier joins the tokens into a camel case identifier, dropping all characters but letters and digits.
+func identifier(tokens []string) string {
+	var b strings.Builder
+	for _, token := range tokens {
+		upper := true
+		for _, r := range token {
+			if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+				upper = true
+				continue
+			}
+			if upper {
+				r = unicode.ToUpper(r)
+				upper = false
+			}
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,114 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aggregator
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/ghodss/yaml"
+	"github.com/go-openapi/spec"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHoistInlineSchemas(t *testing.T) {
+	var sp *spec.Swagger
+	yaml.Unmarshal([]byte(`
+swagger: "2.0"
+paths:
+  /apis/foos:
+    get:
+      operationId: "listFoos"
+      responses:
+        200:
+          description: "OK"
+          schema:
+            type: "object"
+            properties:
+              items:
+                type: "array"
+                items:
+                  type: "object"
+                  properties:
+                    name:
+                      type: "string"
+    post:
+      parameters:
+      - name: "body"
+        in: "body"
+        schema:
+          type: "object"
+          properties:
+            name:
+              type: "string"
+      responses:
+        201:
+          description: "Created"
+definitions:
+  Foo:
+    type: "object"
+    properties:
+      spec:
+        description: "The spec."
+        type: "object"
+        properties:
+          name:
+            type: "string"
+      status:
+        type: "object"
+        properties:
+          phase:
+            type: "string"
+      labels:
+        type: "object"
+        additionalProperties:
+          type: "string"
+  ListFoosResponse200:
+    type: "string"
+`), &sp)
+	orig, _ := cloneSpec(sp)
+	ast := assert.New(t)
+
+	hoisted, err := HoistInlineSchemas()(sp)
+	if !ast.NoError(err) {
+		return
+	}
+	ast.Equal(DebugSpec{orig}, DebugSpec{sp})
+	ast.Equal([]string{"Foo", "FooStatus", "ListFoosResponse200", "ListFoosResponse200ItemsItem", "ListFoosResponse200_v2"}, keys(hoisted.Definitions))
+
+	expected := map[string]string{
+		"ListFoosResponse200ItemsItem": `{"type": "object", "properties": {"name": {"type": "string"}}}`,
+		"ListFoosResponse200_v2":       `{"type": "object", "properties": {"items": {"type": "array", "items": {"$ref": "#/definitions/ListFoosResponse200ItemsItem"}}}}`,
+		"FooStatus":                    `{"type": "object", "properties": {"phase": {"type": "string"}}}`,
+		"Foo": `{"type": "object", "properties": {
+			"spec": {"description": "The spec.", "$ref": "#/definitions/ListFoosResponse200ItemsItem"},
+			"status": {"$ref": "#/definitions/FooStatus"},
+			"labels": {"type": "object", "additionalProperties": {"type": "string"}}}}`,
+	}
+	for name, e := range expected {
+		actual, _ := json.Marshal(hoisted.Definitions[name])
+		ast.JSONEq(e, string(actual), name)
+	}
+	ast.Equal("#/definitions/ListFoosResponse200_v2", hoisted.Paths.Paths["/apis/foos"].Get.Responses.StatusCodeResponses[200].Schema.Ref.String())
+	ast.Equal("#/definitions/ListFoosResponse200ItemsItem", hoisted.Paths.Paths["/apis/foos"].Post.Parameters[0].Schema.Ref.String())
+
+	// Hoisting is idempotent.
+	again, err := HoistInlineSchemas()(hoisted)
+	if ast.NoError(err) {
+		ast.Equal(DebugSpec{hoisted}, DebugSpec{again})
+	}
+}
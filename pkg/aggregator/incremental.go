@@ -0,0 +1,386 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aggregator
+
+import (
+	"sync"
+
+	"github.com/go-openapi/spec"
+)
+
+// IncrementalAggregator keeps the merge of a base spec and a list of named source specs up to date while
+// sources are added, updated and removed. Sources are merged into a copy of the base with MergeSpecsWithOptions.
+//
+// Adding a source only merges that source. Every merge records what the source contributed: the paths, or
+// with MergeOptions.MergeOperations the operations it added, and the shared objects it uses, which are reference
+// counted. Removing a source only undoes its own contributions; the other sources are not merged again. Their
+// objects thus keep the names they got, e.g. Foo_v2, even if the object they were renamed for is gone, and a
+// later source might add an identical object under the freed name. Updating a source removes it and merges the
+// new spec on top of all other sources, keeping its position in Sources. The merged spec therefore only equals
+// merging the current sources in order as long as no source was removed or updated, but it always describes
+// the same operations and models.
+//
+// Every change works on a new merged spec, which shares all paths and shared objects with the previous one,
+// but not the maps and lists holding them. Specs returned by Spec are therefore never mutated.
+type IncrementalAggregator struct {
+	lock sync.Mutex
+
+	opts   MergeOptions
+	merged *spec.Swagger
+
+	// order lists the source names in the order they were added.
+	order   []string
+	sources map[string]*incrementalSource
+	// refCounts counts, per kind of shared object, the sources using each object of the merged spec.
+	// Objects of the base spec are never removed.
+	refCounts map[ObjectKind]map[string]int
+	// createdPaths is true if a merge created the paths of the merged spec. They are removed with the last path.
+	createdPaths bool
+	// adoptedSecurity is true if the merged spec adopted the default security requirements of a source. They
+	// are kept as long as there are paths, which might rely on them.
+	adoptedSecurity bool
+	// pathCreators maps the paths added by sources to these sources.
+	pathCreators map[string]*incrementalSource
+	// pathOrigins are the path items before the first source merged operations into them, with
+	// MergeOptions.MergeOperations. Merging might move path-level parameters into the operations, so these are
+	// restored once all merged operations are removed again.
+	pathOrigins map[string]pathOrigin
+}
+
+// pathOrigin is a path item before sources merged operations into it.
+type pathOrigin struct {
+	pathItem spec.PathItem
+	// creator is the source that added the path, or nil for paths of the base spec.
+	creator *incrementalSource
+}
+
+// incrementalSource is a source spec, and what it contributed to the merged spec.
+type incrementalSource struct {
+	spec   *spec.Swagger
+	report *MergeReport
+	// objects are the names of the shared objects of the merged spec used by the source, per kind.
+	objects map[ObjectKind][]string
+	// operations are the methods of the operations the source added to each path, with
+	// MergeOptions.MergeOperations. Otherwise the source owns the paths in report.AddedPaths.
+	operations map[string][]string
+}
+
+// NewIncrementalAggregator returns an IncrementalAggregator without sources. The base spec is not mutated.
+// The options are used for all merges, with SourceName set to the name of the merged source.
+func NewIncrementalAggregator(base *spec.Swagger, opts MergeOptions) (*IncrementalAggregator, error) {
+	merged, err := deepCopySpec(base)
+	if err != nil {
+		return nil, err
+	}
+	a := &IncrementalAggregator{
+		opts:         opts,
+		merged:       merged,
+		sources:      map[string]*incrementalSource{},
+		refCounts:    map[ObjectKind]map[string]int{},
+		pathCreators: map[string]*incrementalSource{},
+		pathOrigins:  map[string]pathOrigin{},
+	}
+	for _, objects := range sharedObjectSections {
+		a.refCounts[objects.kind] = map[string]int{}
+		for _, name := range objects.names(merged) {
+			a.refCounts[objects.kind][name] = 1
+		}
+	}
+	return a, nil
+}
+
+// Spec returns the merged spec. It must not be mutated. Later changes of the sources do not change it, so it can
+// be used while the sources change.
+func (a *IncrementalAggregator) Spec() *spec.Swagger {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	return a.merged
+}
+
+// Sources returns the names of the sources, in the order they were added.
+func (a *IncrementalAggregator) Sources() []string {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	return append([]string(nil), a.order...)
+}
+
+// Report returns the report of the last merge of the named source, or nil for unknown sources.
+func (a *IncrementalAggregator) Report(name string) *MergeReport {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	if s, found := a.sources[name]; found {
+		return s.report
+	}
+	return nil
+}
+
+// AddOrUpdateSource merges a new source after all other sources, or replaces the spec of an existing source,
+// keeping its position in Sources. The source spec must not be mutated afterwards. On error, the sources and the
+// merged spec are not changed.
+func (a *IncrementalAggregator) AddOrUpdateSource(name string, source *spec.Swagger) (*MergeReport, error) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	published, createdPaths, adoptedSecurity := a.merged, a.createdPaths, a.adoptedSecurity
+	pathCreators, pathOrigins := a.pathCreators, a.pathOrigins
+	a.merged = copyContainers(published)
+	old, exists := a.sources[name]
+	if exists {
+		a.pathCreators = make(map[string]*incrementalSource, len(pathCreators))
+		for path, creator := range pathCreators {
+			a.pathCreators[path] = creator
+		}
+		a.pathOrigins = make(map[string]pathOrigin, len(pathOrigins))
+		for path, origin := range pathOrigins {
+			a.pathOrigins[path] = origin
+		}
+		a.unmerge(old)
+	}
+	s, err := a.merge(name, source)
+	if err != nil {
+		a.merged, a.createdPaths, a.adoptedSecurity = published, createdPaths, adoptedSecurity
+		a.pathCreators, a.pathOrigins = pathCreators, pathOrigins
+		if exists {
+			a.count(old)
+		}
+		return nil, err
+	}
+	if !exists {
+		a.order = append(a.order, name)
+	}
+	a.sources[name] = s
+	return s.report, nil
+}
+
+// RemoveSource removes the named source and everything only it contributed from the merged spec. Removing an
+// unknown source is a no-op.
+func (a *IncrementalAggregator) RemoveSource(name string) error {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	s, exists := a.sources[name]
+	if !exists {
+		return nil
+	}
+	a.merged = copyContainers(a.merged)
+	a.unmerge(s)
+	delete(a.sources, name)
+	for i, n := range a.order {
+		if n == name {
+			a.order = append(a.order[:i:i], a.order[i+1:]...)
+			break
+		}
+	}
+	return nil
+}
+
+// merge merges a source into the merged spec, and records and counts what it contributed. On error, nothing
+// but the merged spec is changed.
+func (a *IncrementalAggregator) merge(name string, source *spec.Swagger) (*incrementalSource, error) {
+	opts := a.opts
+	opts.SourceName = name
+	hadPaths, hadSecurity := a.merged.Paths != nil, a.merged.Security != nil
+	// before are the path items the source might merge operations into.
+	var before map[string]spec.PathItem
+	if opts.MergeOperations && hadPaths && source.Paths != nil {
+		before = map[string]spec.PathItem{}
+		for path := range source.Paths.Paths {
+			if pathItem, found := a.merged.Paths.Paths[path]; found {
+				before[path] = pathItem
+			}
+		}
+	}
+	report, err := MergeSpecsWithOptions(a.merged, source, opts)
+	if err != nil {
+		return nil, err
+	}
+	s := &incrementalSource{
+		spec:    source,
+		report:  report,
+		objects: map[ObjectKind][]string{},
+	}
+	for _, path := range report.AddedPaths {
+		a.pathCreators[path] = s
+	}
+	if opts.MergeOperations && source.Paths != nil {
+		s.operations = map[string][]string{}
+		for path, sourceItem := range source.Paths.Paths {
+			mergedItem, found := a.merged.Paths.Paths[path]
+			if !found {
+				continue
+			}
+			beforeItem, existed := before[path]
+			beforeOps, mergedOps := pathItemOperations(&beforeItem), pathItemOperations(&mergedItem)
+			for i, m := range pathItemOperations(&sourceItem) {
+				// Operations dest had before are never replaced by the ones of source.
+				if *m.op != nil && *beforeOps[i].op == nil && *mergedOps[i].op != nil {
+					s.operations[path] = append(s.operations[path], m.method)
+				}
+			}
+			if _, found := a.pathOrigins[path]; existed && !found && len(s.operations[path]) > 0 {
+				a.pathOrigins[path] = pathOrigin{pathItem: beforeItem, creator: a.pathCreators[path]}
+			}
+		}
+	}
+	if !hadPaths && a.merged.Paths != nil {
+		a.createdPaths = true
+	}
+	if !hadSecurity && a.merged.Security != nil {
+		a.adoptedSecurity = true
+	}
+	for _, objects := range [][]MergedObject{report.Added, report.Reused} {
+		for _, o := range objects {
+			name := o.Name
+			if o.NewName != "" {
+				name = o.NewName
+			}
+			s.objects[o.Kind] = append(s.objects[o.Kind], name)
+		}
+	}
+	a.count(s)
+	return s, nil
+}
+
+// count counts the shared objects used by the source.
+func (a *IncrementalAggregator) count(s *incrementalSource) {
+	for kind, names := range s.objects {
+		for _, name := range names {
+			a.refCounts[kind][name]++
+		}
+	}
+}
+
+// unmerge removes the paths or operations added by the source from the merged spec, as well as the shared
+// objects no other source uses. The source record is not changed, so it can be counted again.
+func (a *IncrementalAggregator) unmerge(s *incrementalSource) {
+	for _, path := range s.report.AddedPaths {
+		if a.pathCreators[path] == s {
+			delete(a.pathCreators, path)
+		}
+	}
+	if s.operations != nil {
+		for path, methods := range s.operations {
+			a.removeOperations(path, methods)
+		}
+	} else {
+		for _, path := range s.report.AddedPaths {
+			delete(a.merged.Paths.Paths, path)
+		}
+	}
+	for _, objects := range sharedObjectSections {
+		refCounts := a.refCounts[objects.kind]
+		for _, name := range s.objects[objects.kind] {
+			if refCounts[name]--; refCounts[name] == 0 {
+				delete(refCounts, name)
+				objects.remove(a.merged, name)
+			}
+		}
+	}
+	noPaths := a.merged.Paths == nil || len(a.merged.Paths.Paths) == 0
+	if a.createdPaths && noPaths {
+		a.merged.Paths = nil
+		a.createdPaths = false
+	}
+	if a.adoptedSecurity && noPaths {
+		a.merged.Security = nil
+		a.adoptedSecurity = false
+	}
+}
+
+// removeOperations removes the operations of the given methods from the path of the merged spec, and the
+// path if no operation is left. If the operations left are the ones the path had before sources merged
+// operations into it, the original path item is restored.
+func (a *IncrementalAggregator) removeOperations(path string, methods []string) {
+	pathItem := a.merged.Paths.Paths[path]
+	for _, m := range pathItemOperations(&pathItem) {
+		for _, method := range methods {
+			if m.method == method {
+				*m.op = nil
+			}
+		}
+	}
+	origin, found := a.pathOrigins[path]
+	switch {
+	case !hasOperations(&pathItem):
+		delete(a.merged.Paths.Paths, path)
+		delete(a.pathOrigins, path)
+	case found && origin.creator != nil && a.pathCreators[path] != origin.creator:
+		// The source that added the original path item is removed.
+		a.merged.Paths.Paths[path] = pathItem
+		delete(a.pathOrigins, path)
+	case found && sameMethods(&pathItem, &origin.pathItem):
+		a.merged.Paths.Paths[path] = origin.pathItem
+		delete(a.pathOrigins, path)
+	default:
+		a.merged.Paths.Paths[path] = pathItem
+	}
+}
+
+// sameMethods returns true if both path items have operations for the same methods.
+func sameMethods(a, b *spec.PathItem) bool {
+	opsB := pathItemOperations(b)
+	for i, m := range pathItemOperations(a) {
+		if (*m.op == nil) != (*opsB[i].op == nil) {
+			return false
+		}
+	}
+	return true
+}
+
+// copyContainers returns a copy of the spec with copies of the maps and lists that merges and unmerges modify,
+// sharing their elements with the input.
+func copyContainers(sp *spec.Swagger) *spec.Swagger {
+	ret := *sp
+	if sp.Paths != nil {
+		paths := *sp.Paths
+		if sp.Paths.Paths != nil {
+			paths.Paths = make(map[string]spec.PathItem, len(sp.Paths.Paths))
+			for k, v := range sp.Paths.Paths {
+				paths.Paths[k] = v
+			}
+		}
+		ret.Paths = &paths
+	}
+	if sp.Definitions != nil {
+		ret.Definitions = make(spec.Definitions, len(sp.Definitions))
+		for k, v := range sp.Definitions {
+			ret.Definitions[k] = v
+		}
+	}
+	if sp.Parameters != nil {
+		ret.Parameters = make(map[string]spec.Parameter, len(sp.Parameters))
+		for k, v := range sp.Parameters {
+			ret.Parameters[k] = v
+		}
+	}
+	if sp.Responses != nil {
+		ret.Responses = make(map[string]spec.Response, len(sp.Responses))
+		for k, v := range sp.Responses {
+			ret.Responses[k] = v
+		}
+	}
+	if sp.SecurityDefinitions != nil {
+		ret.SecurityDefinitions = make(spec.SecurityDefinitions, len(sp.SecurityDefinitions))
+		for k, v := range sp.SecurityDefinitions {
+			ret.SecurityDefinitions[k] = v
+		}
+	}
+	if sp.Tags != nil {
+		ret.Tags = append(make([]spec.Tag, 0, len(sp.Tags)), sp.Tags...)
+	}
+	return &ret
+}
@@ -0,0 +1,253 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aggregator
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"github.com/ghodss/yaml"
+	"github.com/go-openapi/spec"
+	"github.com/stretchr/testify/assert"
+)
+
+func incrementalTestSpec(path, fooType, tag string) *spec.Swagger {
+	var s *spec.Swagger
+	yaml.Unmarshal([]byte(fmt.Sprintf(`
+swagger: "2.0"
+paths:
+  %s:
+    get:
+      tags: ["%s"]
+      responses:
+        200:
+          description: "OK"
+          schema:
+            $ref: "#/definitions/Foo"
+    post:
+      responses:
+        200:
+          description: "OK"
+          schema:
+            $ref: "#/definitions/Status"
+definitions:
+  Foo:
+    type: "%s"
+  Status:
+    type: "object"
+tags:
+- name: "%s"
+`, path, tag, fooType, tag)), &s)
+	return s
+}
+
+// fullMerge merges the named sources into a copy of base, in order.
+func fullMerge(t *testing.T, base *spec.Swagger, order []string, sources map[string]*spec.Swagger) string {
+	merged, _ := cloneSpec(base)
+	for _, name := range order {
+		if _, err := MergeSpecsWithOptions(merged, sources[name], MergeOptions{SourceName: name}); err != nil {
+			t.Fatalf("failed to merge %s: %v", name, err)
+		}
+	}
+	bytes, _ := json.Marshal(merged)
+	return string(bytes)
+}
+
+// checkIncrementalSpec checks that the merged spec has the operations of the base and all sources, which are
+// built by incrementalTestSpec, that their references resolve to the definitions and tags of their own spec,
+// and that it has no unused definitions and no other tags.
+func checkIncrementalSpec(t *testing.T, merged, base *spec.Swagger, sources map[string]*spec.Swagger) bool {
+	ast := assert.New(t)
+	ok := true
+	fooTypes, tags := map[string]bool{}, map[string]bool{}
+	paths := map[string]bool{}
+	for _, s := range append([]*spec.Swagger{base}, specs(sources)...) {
+		fooType := s.Definitions["Foo"].Type[0]
+		fooTypes[fooType] = true
+		for path, pathItem := range s.Paths.Paths {
+			paths[path] = true
+			tags[pathItem.Get.Tags[0]] = true
+			mergedItem, found := merged.Paths.Paths[path]
+			if !ast.True(found, "missing path %s", path) {
+				ok = false
+				continue
+			}
+			get := mergedItem.Get.Responses.StatusCodeResponses[200].Schema.Ref.String()
+			post := mergedItem.Post.Responses.StatusCodeResponses[200].Schema.Ref.String()
+			ok = ast.Equal(spec.StringOrArray{fooType}, merged.Definitions[get[len(definitionPrefix):]].Type, path) && ok
+			ok = ast.Equal(s.Definitions["Status"], merged.Definitions[post[len(definitionPrefix):]], path) && ok
+			ok = ast.Equal(pathItem.Get.Tags, mergedItem.Get.Tags, path) && ok
+		}
+	}
+	ok = ast.Len(merged.Paths.Paths, len(paths)) && ok
+	// A Foo definition per type, and Status. Names freed by removals can make later sources add a Foo
+	// identical to one renamed before, but all definitions must be used.
+	mergedTypes := map[string]bool{}
+	for name, def := range merged.Definitions {
+		if name != "Status" {
+			mergedTypes[def.Type[0]] = true
+		}
+	}
+	ok = ast.Equal(fooTypes, mergedTypes) && ok
+	used := map[string]bool{}
+	for _, pathItem := range merged.Paths.Paths {
+		for _, op := range []*spec.Operation{pathItem.Get, pathItem.Post} {
+			ref := op.Responses.StatusCodeResponses[200].Schema.Ref.String()
+			used[ref[len(definitionPrefix):]] = true
+		}
+	}
+	ok = ast.Len(merged.Definitions, len(used)) && ok
+	mergedTags := map[string]bool{}
+	for _, tag := range merged.Tags {
+		mergedTags[tag.Name] = true
+	}
+	ok = ast.Equal(tags, mergedTags) && ok
+	return ok
+}
+
+func specs(sources map[string]*spec.Swagger) []*spec.Swagger {
+	ret := make([]*spec.Swagger, 0, len(sources))
+	for _, s := range sources {
+		ret = append(ret, s)
+	}
+	return ret
+}
+
+func TestIncrementalAggregator(t *testing.T) {
+	ast := assert.New(t)
+	base := incrementalTestSpec("/base", "string", "base")
+	a, err := NewIncrementalAggregator(base, MergeOptions{})
+	if !ast.NoError(err) {
+		return
+	}
+	expected := map[string]*spec.Swagger{}
+	check := func() {
+		checkIncrementalSpec(t, a.Spec(), base, expected)
+	}
+
+	for _, s := range []struct{ name, fooType string }{{"a", "integer"}, {"b", "boolean"}, {"c", "integer"}} {
+		expected[s.name] = incrementalTestSpec("/"+s.name, s.fooType, s.name)
+		report, err := a.AddOrUpdateSource(s.name, expected[s.name])
+		ast.NoError(err)
+		ast.Equal(s.name, report.Source)
+		// Without removals, the merged spec is the one of merging all sources in order.
+		actual, _ := json.Marshal(a.Spec())
+		ast.Equal(fullMerge(t, base, a.Sources(), expected), string(actual))
+	}
+	ast.Equal(spec.StringOrArray{"integer"}, a.Spec().Definitions["Foo_v2"].Type)
+	ast.Equal(spec.StringOrArray{"boolean"}, a.Spec().Definitions["Foo_v3"].Type)
+
+	// Removing a keeps the integer Foo used by c, and the name it got.
+	ast.NoError(a.RemoveSource("a"))
+	delete(expected, "a")
+	ast.Equal([]string{"b", "c"}, a.Sources())
+	check()
+	ast.Equal(spec.StringOrArray{"integer"}, a.Spec().Definitions["Foo_v2"].Type)
+	ast.Equal(spec.StringOrArray{"boolean"}, a.Spec().Definitions["Foo_v3"].Type)
+	ast.NotContains(a.Spec().Paths.Paths, "/a")
+
+	// Updating keeps the position of the source, and does not touch the contributions of c.
+	cPath := a.Spec().Paths.Paths["/c"]
+	expected["b"] = incrementalTestSpec("/b2", "string", "b")
+	_, err = a.AddOrUpdateSource("b", expected["b"])
+	ast.NoError(err)
+	ast.Equal([]string{"b", "c"}, a.Sources())
+	check()
+	ast.NotContains(a.Spec().Paths.Paths, "/b")
+	ast.NotContains(a.Spec().Definitions, "Foo_v3")
+	ast.Equal(cPath, a.Spec().Paths.Paths["/c"])
+
+	// A failing update changes nothing.
+	before := a.Spec()
+	_, err = a.AddOrUpdateSource("b", incrementalTestSpec("/c", "string", "b"))
+	ast.Error(err)
+	ast.True(before == a.Spec())
+	check()
+	_, err = a.AddOrUpdateSource("d", incrementalTestSpec("/base", "string", "d"))
+	ast.Error(err)
+	ast.Equal([]string{"b", "c"}, a.Sources())
+	check()
+	// The reference counts are intact after the failed update.
+	ast.NoError(a.RemoveSource("c"))
+	delete(expected, "c")
+	check()
+
+	ast.NoError(a.RemoveSource("b"))
+	ast.NoError(a.RemoveSource("unknown"))
+	delete(expected, "b")
+	check()
+	ast.Equal(base, a.Spec())
+}
+
+func TestIncrementalAggregatorSpecIsNotMutated(t *testing.T) {
+	ast := assert.New(t)
+	a, err := NewIncrementalAggregator(incrementalTestSpec("/base", "string", "base"), MergeOptions{})
+	if !ast.NoError(err) {
+		return
+	}
+	_, err = a.AddOrUpdateSource("a", incrementalTestSpec("/a", "integer", "a"))
+	ast.NoError(err)
+	before := a.Spec()
+	beforeJSON, _ := json.Marshal(before)
+
+	_, err = a.AddOrUpdateSource("b", incrementalTestSpec("/b", "boolean", "b"))
+	ast.NoError(err)
+	ast.NoError(a.RemoveSource("a"))
+	_, err = a.AddOrUpdateSource("b", incrementalTestSpec("/base", "string", "b"))
+	ast.Error(err)
+
+	afterJSON, _ := json.Marshal(before)
+	ast.Equal(string(beforeJSON), string(afterJSON))
+	ast.NotContains(a.Spec().Paths.Paths, "/a")
+}
+
+func TestIncrementalAggregatorRandomized(t *testing.T) {
+	r := rand.New(rand.NewSource(42))
+	types := []string{"string", "integer", "boolean"}
+	base := incrementalTestSpec("/base", "string", "base")
+	a, err := NewIncrementalAggregator(base, MergeOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]*spec.Swagger{}
+	for i := 0; i < 200; i++ {
+		name := fmt.Sprintf("s%d", r.Intn(6))
+		if r.Intn(3) == 0 {
+			if err := a.RemoveSource(name); err != nil {
+				t.Fatal(err)
+			}
+			delete(expected, name)
+		} else {
+			s := incrementalTestSpec("/"+name+"/"+types[r.Intn(2)], types[r.Intn(len(types))], types[r.Intn(len(types))])
+			if _, err := a.AddOrUpdateSource(name, s); err != nil {
+				t.Fatal(err)
+			}
+			expected[name] = s
+		}
+		if !checkIncrementalSpec(t, a.Spec(), base, expected) {
+			t.Fatalf("step %d", i)
+		}
+	}
+	for name := range expected {
+		if err := a.RemoveSource(name); err != nil {
+			t.Fatal(err)
+		}
+	}
+	assert.Equal(t, base, a.Spec())
+}
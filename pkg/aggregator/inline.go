@@ -0,0 +1,191 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aggregator
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/go-openapi/spec"
+)
+
+// InlineOptions configures InlineSchema and InlineRefs.
+type InlineOptions struct {
+	// MaxDepth limits the number of nested references expanded into each schema. Zero means no limit.
+	// References to definitions being expanded already, i.e. cycles, are never expanded.
+	MaxDepth int
+
+	// ReplaceUnexpanded replaces references that are not expanded, because of cycles or MaxDepth, by an empty
+	// schema allowing any value, so that the result has no references to definitions. Otherwise they are kept.
+	ReplaceUnexpanded bool
+
+	// UnexpandedMarker is a vendor extension, e.g. x-kubernetes-unexpanded-ref, set to the name of the
+	// referenced definition on schemas not expanded because of cycles or MaxDepth. Empty means no marker.
+	UnexpandedMarker string
+}
+
+// InlineSchema returns a copy of the schema with references to the given definitions replaced by a copy of the
+// definition, recursively. Descriptions of the referencing schemas are kept, all other fields next to the
+// reference are replaced. References to missing definitions are an error, references not starting with
+// #/definitions/ are kept. The inputs are not mutated.
+func InlineSchema(definitions spec.Definitions, schema *spec.Schema, opts InlineOptions) (*spec.Schema, error) {
+	ret, err := deepCopySchema(schema)
+	if err != nil {
+		return nil, err
+	}
+	if err := (&inliner{definitions: definitions, opts: opts, expanding: map[string]bool{}}).inline(ret, 0); err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
+
+// InlineRefs returns a SpecTransformer inlining the definitions into all schemas of the paths, shared parameters
+// and shared responses, and into the definitions themselves, like InlineSchema. References of definitions to
+// themselves are cycles, and not expanded. The definitions are kept, chain RemoveUnusedDefinitions to remove
+// the ones that are no longer referenced.
+func InlineRefs(opts InlineOptions) SpecTransformer {
+	return func(sp *spec.Swagger) (*spec.Swagger, error) {
+		var errs []string
+		inlineInto := func(pointer string, s *spec.Schema, expanding map[string]bool) {
+			if s == nil {
+				return
+			}
+			if err := (&inliner{definitions: sp.Definitions, opts: opts, expanding: expanding}).inline(s, 0); err != nil {
+				errs = append(errs, fmt.Sprintf("%s: %v", pointer, err))
+			}
+		}
+		ret, err := RewriteSpec(sp, &SpecVisitor{
+			Parameter: func(pointer string, p *spec.Parameter) {
+				inlineInto(pointerTo(pointer, "schema"), p.Schema, map[string]bool{})
+			},
+			Response: func(pointer string, r *spec.Response) {
+				inlineInto(pointerTo(pointer, "schema"), r.Schema, map[string]bool{})
+			},
+		})
+		if err != nil {
+			return nil, err
+		}
+		// Definitions are expanded from the ones of sp, so that expansions of earlier definitions do not leak into later ones.
+		for _, name := range sortedMapKeys(ret.Definitions) {
+			def := ret.Definitions[name]
+			inlineInto(pointerTo("/definitions", name), &def, map[string]bool{name: true})
+			ret.Definitions[name] = def
+		}
+		if len(errs) > 0 {
+			return nil, fmt.Errorf("failed to inline references: %s", strings.Join(errs, ", "))
+		}
+		return ret, nil
+	}
+}
+
+// inliner expands references to definitions in place.
+type inliner struct {
+	definitions spec.Definitions
+	opts        InlineOptions
+	// expanding are the definitions expanded by the ancestors of the current schema.
+	expanding map[string]bool
+}
+
+func (in *inliner) inline(s *spec.Schema, depth int) error {
+	if ref := s.Ref.String(); strings.HasPrefix(ref, definitionPrefix) {
+		name := ref[len(definitionPrefix):]
+		def, found := in.definitions[name]
+		if !found {
+			return fmt.Errorf("reference to missing definition %q", name)
+		}
+		if in.expanding[name] || (in.opts.MaxDepth > 0 && depth >= in.opts.MaxDepth) {
+			in.unexpanded(s, name)
+			return nil
+		}
+		expanded, err := deepCopySchema(&def)
+		if err != nil {
+			return err
+		}
+		if s.Description != "" {
+			expanded.Description = s.Description
+		}
+		*s = *expanded
+
+		in.expanding[name] = true
+		defer delete(in.expanding, name)
+		depth++
+	}
+	var err error
+	forEachSubSchema(s, func(sub *spec.Schema) {
+		if err == nil {
+			err = in.inline(sub, depth)
+		}
+	})
+	return err
+}
+
+// unexpanded handles a reference to the named definition that is not expanded.
+func (in *inliner) unexpanded(s *spec.Schema, name string) {
+	if in.opts.ReplaceUnexpanded {
+		*s = spec.Schema{SchemaProps: spec.SchemaProps{Description: s.Description}}
+	}
+	if in.opts.UnexpandedMarker != "" {
+		s.AddExtension(in.opts.UnexpandedMarker, name)
+	}
+}
+
+// forEachSubSchema calls f on the direct sub-schemas of the schema, and writes them back.
+func forEachSubSchema(s *spec.Schema, f func(sub *spec.Schema)) {
+	for _, m := range []map[string]spec.Schema{s.Definitions, s.Properties, s.PatternProperties} {
+		for _, k := range sortedMapKeys(m) {
+			sub := m[k]
+			f(&sub)
+			m[k] = sub
+		}
+	}
+	for _, l := range [][]spec.Schema{s.AllOf, s.AnyOf, s.OneOf} {
+		for i := range l {
+			f(&l[i])
+		}
+	}
+	if s.Not != nil {
+		f(s.Not)
+	}
+	if s.Items != nil {
+		if s.Items.Schema != nil {
+			f(s.Items.Schema)
+		}
+		for i := range s.Items.Schemas {
+			f(&s.Items.Schemas[i])
+		}
+	}
+	if s.AdditionalProperties != nil && s.AdditionalProperties.Schema != nil {
+		f(s.AdditionalProperties.Schema)
+	}
+	if s.AdditionalItems != nil && s.AdditionalItems.Schema != nil {
+		f(s.AdditionalItems.Schema)
+	}
+}
+
+// deepCopySchema returns a copy of the schema that shares no data structures with the input.
+func deepCopySchema(s *spec.Schema) (*spec.Schema, error) {
+	bytes, err := json.Marshal(s)
+	if err != nil {
+		return nil, err
+	}
+	ret := &spec.Schema{}
+	if err := json.Unmarshal(bytes, ret); err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
@@ -0,0 +1,163 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aggregator
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/ghodss/yaml"
+	"github.com/go-openapi/spec"
+	"github.com/stretchr/testify/assert"
+)
+
+const inlineTestSpec = `
+swagger: "2.0"
+paths:
+  /props:
+    get:
+      responses:
+        200:
+          description: "OK"
+          schema:
+            $ref: "#/definitions/JSONSchemaProps"
+definitions:
+  JSONSchemaProps:
+    type: "object"
+    properties:
+      meta:
+        description: "Metadata."
+        $ref: "#/definitions/Meta"
+      properties:
+        type: "object"
+        additionalProperties:
+          $ref: "#/definitions/JSONSchemaProps"
+  Meta:
+    type: "object"
+    properties:
+      labels:
+        $ref: "#/definitions/Labels"
+  Labels:
+    type: "object"
+    description: "Labels."
+`
+
+func TestInlineSchema(t *testing.T) {
+	var sp *spec.Swagger
+	yaml.Unmarshal([]byte(inlineTestSpec), &sp)
+	orig, _ := cloneSpec(sp)
+	ref := spec.Schema{SchemaProps: spec.SchemaProps{Ref: spec.MustCreateRef("#/definitions/JSONSchemaProps")}}
+
+	tcs := []struct {
+		name     string
+		opts     InlineOptions
+		expected string
+	}{
+		{
+			name: "cycle",
+			expected: `
+type: "object"
+properties:
+  meta:
+    description: "Metadata."
+    type: "object"
+    properties:
+      labels:
+        type: "object"
+        description: "Labels."
+  properties:
+    type: "object"
+    additionalProperties:
+      $ref: "#/definitions/JSONSchemaProps"
+`,
+		},
+		{
+			name: "depth with marker",
+			opts: InlineOptions{MaxDepth: 2, UnexpandedMarker: "x-unexpanded"},
+			expected: `
+type: "object"
+properties:
+  meta:
+    description: "Metadata."
+    type: "object"
+    properties:
+      labels:
+        $ref: "#/definitions/Labels"
+        x-unexpanded: "Labels"
+  properties:
+    type: "object"
+    additionalProperties:
+      $ref: "#/definitions/JSONSchemaProps"
+      x-unexpanded: "JSONSchemaProps"
+`,
+		},
+		{
+			name: "replace unexpanded",
+			opts: InlineOptions{MaxDepth: 1, ReplaceUnexpanded: true},
+			expected: `
+type: "object"
+properties:
+  meta:
+    description: "Metadata."
+  properties:
+    type: "object"
+    additionalProperties: {}
+`,
+		},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			expectedJSON, err := yaml.YAMLToJSON([]byte(tc.expected))
+			if err != nil {
+				t.Fatal(err)
+			}
+			inlined, err := InlineSchema(sp.Definitions, &ref, tc.opts)
+			if assert.NoError(t, err) {
+				actualJSON, _ := json.Marshal(inlined)
+				assert.JSONEq(t, string(expectedJSON), string(actualJSON))
+			}
+			assert.Equal(t, DebugSpec{orig}, DebugSpec{sp})
+		})
+	}
+
+	missing := spec.Schema{SchemaProps: spec.SchemaProps{Ref: spec.MustCreateRef("#/definitions/Missing")}}
+	_, err := InlineSchema(sp.Definitions, &missing, InlineOptions{})
+	assert.EqualError(t, err, `reference to missing definition "Missing"`)
+}
+
+func TestInlineRefs(t *testing.T) {
+	var sp *spec.Swagger
+	yaml.Unmarshal([]byte(inlineTestSpec), &sp)
+	orig, _ := cloneSpec(sp)
+	ast := assert.New(t)
+
+	inlined, err := ChainTransformers(InlineRefs(InlineOptions{}), RemoveUnusedDefinitions())(sp)
+	if !ast.NoError(err) {
+		return
+	}
+	ast.Equal(DebugSpec{orig}, DebugSpec{sp})
+	schema := inlined.Paths.Paths["/props"].Get.Responses.StatusCodeResponses[200].Schema
+	ast.Equal("", schema.Ref.String())
+	ast.Equal("#/definitions/JSONSchemaProps", schema.Properties["properties"].AdditionalProperties.Schema.Ref.String())
+	ast.Equal([]string{"JSONSchemaProps"}, keys(inlined.Definitions))
+	ast.Equal("#/definitions/JSONSchemaProps", inlined.Definitions["JSONSchemaProps"].Properties["properties"].AdditionalProperties.Schema.Ref.String())
+	ast.Equal("Labels.", inlined.Definitions["JSONSchemaProps"].Properties["meta"].Properties["labels"].Description)
+
+	sp.Definitions["Meta"].Properties["labels"] = spec.Schema{SchemaProps: spec.SchemaProps{Ref: spec.MustCreateRef("#/definitions/Missing")}}
+	_, err = InlineRefs(InlineOptions{})(sp)
+	ast.Error(err)
+}
@@ -0,0 +1,179 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aggregator
+
+import (
+	"sort"
+)
+
+// MergeOptions configures MergeSpecsWithOptions.
+type MergeOptions struct {
+	// SourceName identifies the source spec in the report, e.g. the name of the aggregated API server.
+	SourceName string
+
+	// IgnorePathConflicts keeps the paths of dest on path conflicts, instead of failing. With MergeOperations,
+	// it keeps the operations of dest on operation conflicts.
+	IgnorePathConflicts bool
+
+	// MergeOperations merges the operations of paths both dest and source have, instead of treating the paths
+	// as conflicts. Only operations of the same path and method conflict then. If the path-level parameters of
+	// such paths differ, they are moved into the operations.
+	MergeOperations bool
+
+	// FailOnConflicts fails on conflicting definitions, shared parameters, responses and security
+	// definitions, instead of renaming them.
+	FailOnConflicts bool
+
+	// ConflictRenamer chooses the names of renamed conflicting objects. Defaults to VersionSuffixRenamer.
+	ConflictRenamer ConflictRenamer
+
+	// OperationIDConflicts decides how operations of source whose ID is used by dest as well are handled.
+	// Defaults to OperationIDConflictKeep.
+	OperationIDConflicts OperationIDConflictPolicy
+
+	// DefinitionEquivalence decides whether a definition of source is the same model as a definition of dest,
+	// so that it is reused instead of renamed. Defaults to ExactEquivalence(), like MergeSpecs. Use
+	// CanonicalEquivalence to also reuse definitions that only differ in their representation.
+	DefinitionEquivalence DefinitionEquivalence
+}
+
+func (o *MergeOptions) definitionEquivalence() DefinitionEquivalence {
+	if o.DefinitionEquivalence == nil {
+		return defaultDefinitionEquivalence
+	}
+	return o.DefinitionEquivalence
+}
+
+// OperationIDConflictPolicy is a way to handle operation IDs used by both specs of a merge.
+type OperationIDConflictPolicy string
+
+const (
+	// OperationIDConflictKeep keeps duplicate operation IDs. They are only reported.
+	OperationIDConflictKeep OperationIDConflictPolicy = ""
+	// OperationIDConflictFail fails the merge on duplicate operation IDs.
+	OperationIDConflictFail OperationIDConflictPolicy = "Fail"
+	// OperationIDConflictPrefix prefixes duplicate operation IDs of the source with the source name and an
+	// underscore, e.g. metrics_getAPIResources. A _v2, _v3 and so on suffix is appended if that ID is used as
+	// well. Without source name, it behaves like OperationIDConflictSuffix.
+	OperationIDConflictPrefix OperationIDConflictPolicy = "Prefix"
+	// OperationIDConflictSuffix appends _v2, _v3 and so on to duplicate operation IDs of the source.
+	OperationIDConflictSuffix OperationIDConflictPolicy = "Suffix"
+)
+
+// ObjectKind is the kind of a named object shared by the paths of a spec.
+type ObjectKind string
+
+// The kinds of shared objects merged by MergeSpecsWithOptions.
+const (
+	DefinitionKind         ObjectKind = "definition"
+	ParameterKind          ObjectKind = "parameter"
+	ResponseKind           ObjectKind = "response"
+	SecurityDefinitionKind ObjectKind = "securityDefinition"
+	TagKind                ObjectKind = "tag"
+)
+
+// RenameReason explains why an object was renamed during a merge.
+type RenameReason string
+
+const (
+	// RenameReasonConflict means that dest has a different object of the same name.
+	RenameReasonConflict RenameReason = "conflicts with a different object of the same name"
+	// RenameReasonReused means that dest has an identical object, renamed in an earlier merge.
+	RenameReasonReused RenameReason = "identical to an object renamed in an earlier merge"
+)
+
+// MergedObject describes what happened to a shared object of the source spec during a merge.
+type MergedObject struct {
+	Kind ObjectKind
+	// Name is the name of the object in the source spec.
+	Name string
+	// NewName is the name of the object in the merged spec, if it differs from Name.
+	NewName string
+	// Reason is set for renamed objects.
+	Reason RenameReason
+}
+
+// MergeReport describes what a merge contributed to the destination spec. All lists are sorted.
+type MergeReport struct {
+	// Source is MergeOptions.SourceName.
+	Source string
+
+	// AddedPaths are the paths of the source added to the destination.
+	AddedPaths []string
+	// SkippedPaths are the paths of the source dropped because the destination already had them.
+	SkippedPaths []string
+	// AddedOperations are the operations of the source added to paths the destination already had, with
+	// MergeOperations.
+	AddedOperations []PathOperation
+	// SkippedOperations are the operations of the source dropped because the destination already had them,
+	// with MergeOperations.
+	SkippedOperations []PathOperation
+
+	// OperationIDConflicts are the operations of the source whose ID was used by the destination already.
+	OperationIDConflicts []OperationIDConflict
+
+	// Added are the shared objects of the source added to the destination.
+	Added []MergedObject
+	// Reused are the shared objects of the source not added because the destination already had an identical
+	// one, under the same name or under NewName.
+	Reused []MergedObject
+	// Renamed are the shared objects of the source whose references were renamed. They are also listed in
+	// Added or Reused.
+	Renamed []MergedObject
+}
+
+// PathOperation identifies an operation by path and lower case HTTP method.
+type PathOperation struct {
+	Path   string
+	Method string
+}
+
+// OperationIDConflict is an operation of the source spec of a merge, whose ID was used by the destination spec.
+type OperationIDConflict struct {
+	PathOperation
+	OperationID string
+	// NewOperationID is the ID of the operation in the merged spec, if it was changed.
+	NewOperationID string
+}
+
+func (r *MergeReport) sort() {
+	sort.Strings(r.AddedPaths)
+	sort.Strings(r.SkippedPaths)
+	sortPathOperations(r.AddedOperations)
+	sortPathOperations(r.SkippedOperations)
+	sort.Slice(r.OperationIDConflicts, func(i, j int) bool {
+		a, b := r.OperationIDConflicts[i].PathOperation, r.OperationIDConflicts[j].PathOperation
+		return a.Path < b.Path || a.Path == b.Path && a.Method < b.Method
+	})
+	for _, objects := range [][]MergedObject{r.Added, r.Reused, r.Renamed} {
+		sort.Slice(objects, func(i, j int) bool {
+			if objects[i].Kind != objects[j].Kind {
+				return objects[i].Kind < objects[j].Kind
+			}
+			return objects[i].Name < objects[j].Name
+		})
+	}
+}
+
+func sortPathOperations(ops []PathOperation) {
+	sort.Slice(ops, func(i, j int) bool {
+		if ops[i].Path != ops[j].Path {
+			return ops[i].Path < ops[j].Path
+		}
+		return ops[i].Method < ops[j].Method
+	})
+}
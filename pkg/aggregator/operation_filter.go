@@ -0,0 +1,214 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aggregator
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+
+	"github.com/go-openapi/spec"
+
+	"k8s.io/kube-openapi/pkg/util"
+)
+
+// OperationPredicate selects operations by their path, lower case HTTP method and content. It must not mutate
+// the operation.
+type OperationPredicate func(path, method string, op *spec.Operation) bool
+
+// FilterSpecByOperations removes the operations the predicate does not select, as well as path items left without
+// operations. All definitions, shared parameters and shared responses used by removed operations and not used
+// anywhere else are removed as well.
+// It does not modify the input, but the output shares data structures with the input.
+func FilterSpecByOperations(sp *spec.Swagger, keep OperationPredicate) *spec.Swagger {
+	return filterPaths(sp, func(path string, pathItem spec.PathItem) (spec.PathItem, bool) {
+		for _, m := range pathItemOperations(&pathItem) {
+			if *m.op != nil && !keep(path, m.method, *m.op) {
+				*m.op = nil
+			}
+		}
+		return pathItem, hasOperations(&pathItem)
+	})
+}
+
+// And selects operations selected by all predicates.
+func And(predicates ...OperationPredicate) OperationPredicate {
+	return func(path, method string, op *spec.Operation) bool {
+		for _, p := range predicates {
+			if !p(path, method, op) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// Or selects operations selected by any of the predicates.
+func Or(predicates ...OperationPredicate) OperationPredicate {
+	return func(path, method string, op *spec.Operation) bool {
+		for _, p := range predicates {
+			if p(path, method, op) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// Not selects operations not selected by the predicate.
+func Not(predicate OperationPredicate) OperationPredicate {
+	return func(path, method string, op *spec.Operation) bool {
+		return !predicate(path, method, op)
+	}
+}
+
+// HasPathPrefix selects operations whose path starts with one of the prefixes, like FilterSpecByPaths.
+func HasPathPrefix(prefixes ...string) OperationPredicate {
+	trie := util.NewTrie(prefixes)
+	return func(path, _ string, _ *spec.Operation) bool {
+		return trie.HasPrefix(path)
+	}
+}
+
+// HasMethod selects operations of one of the HTTP methods, ignoring case.
+func HasMethod(methods ...string) OperationPredicate {
+	selected := map[string]bool{}
+	for _, m := range methods {
+		selected[strings.ToLower(m)] = true
+	}
+	return func(_, method string, _ *spec.Operation) bool {
+		return selected[method]
+	}
+}
+
+// HasTag selects operations with at least one of the tags.
+func HasTag(tags ...string) OperationPredicate {
+	selected := map[string]bool{}
+	for _, t := range tags {
+		selected[t] = true
+	}
+	return func(_, _ string, op *spec.Operation) bool {
+		for _, t := range op.Tags {
+			if selected[t] {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// IsDeprecated selects deprecated operations.
+func IsDeprecated() OperationPredicate {
+	return func(_, _ string, op *spec.Operation) bool {
+		return op.Deprecated
+	}
+}
+
+// HasExtension selects operations with the vendor extension, e.g. x-kubernetes-action, ignoring the case of the
+// key. If values are given, the extension must have one of them, compared by their JSON representation.
+func HasExtension(key string, values ...interface{}) OperationPredicate {
+	normalizedValues := make([]interface{}, 0, len(values))
+	for _, v := range values {
+		if n, err := normalizeJSON(v); err == nil {
+			normalizedValues = append(normalizedValues, n)
+		}
+	}
+	return func(_, _ string, op *spec.Operation) bool {
+		v, found := lookupExtension(op.Extensions, key)
+		if !found {
+			return false
+		}
+		if len(values) == 0 {
+			return true
+		}
+		n, err := normalizeJSON(v)
+		if err != nil {
+			return false
+		}
+		for _, expected := range normalizedValues {
+			if reflect.DeepEqual(n, expected) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// GroupVersionKind is an entry of the x-kubernetes-group-version-kind extension.
+type GroupVersionKind struct {
+	Group   string `json:"group"`
+	Version string `json:"version"`
+	Kind    string `json:"kind"`
+}
+
+const groupVersionKindExtension = "x-kubernetes-group-version-kind"
+
+// HasGroupVersionKind selects operations whose x-kubernetes-group-version-kind extension matches the given one.
+// Empty fields of the given group version kind match any value, so that e.g. all operations of a group
+// can be selected. The extension can either be a single group version kind or a list of them.
+func HasGroupVersionKind(gvk GroupVersionKind) OperationPredicate {
+	return func(_, _ string, op *spec.Operation) bool {
+		v, found := lookupExtension(op.Extensions, groupVersionKindExtension)
+		if !found {
+			return false
+		}
+		bytes, err := json.Marshal(v)
+		if err != nil {
+			return false
+		}
+		var gvks []GroupVersionKind
+		if err := json.Unmarshal(bytes, &gvks); err != nil {
+			var single GroupVersionKind
+			if err := json.Unmarshal(bytes, &single); err != nil {
+				return false
+			}
+			gvks = []GroupVersionKind{single}
+		}
+		for _, actual := range gvks {
+			if (gvk.Group == "" || gvk.Group == actual.Group) &&
+				(gvk.Version == "" || gvk.Version == actual.Version) &&
+				(gvk.Kind == "" || gvk.Kind == actual.Kind) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// lookupExtension returns the value of the vendor extension, ignoring the case of the key.
+func lookupExtension(e spec.Extensions, key string) (interface{}, bool) {
+	if v, found := e[key]; found {
+		return v, true
+	}
+	for k, v := range e {
+		if strings.EqualFold(k, key) {
+			return v, true
+		}
+	}
+	return nil, false
+}
+
+// normalizeJSON returns the value as decoded from its JSON representation.
+func normalizeJSON(v interface{}) (interface{}, error) {
+	bytes, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var ret interface{}
+	err = json.Unmarshal(bytes, &ret)
+	return ret, err
+}
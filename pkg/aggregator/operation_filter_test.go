@@ -0,0 +1,151 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aggregator
+
+import (
+	"sort"
+	"testing"
+
+	"github.com/ghodss/yaml"
+	"github.com/go-openapi/spec"
+	"github.com/stretchr/testify/assert"
+)
+
+const operationFilterSpec = `
+swagger: "2.0"
+paths:
+  /apis/apps/v1/deployments:
+    get:
+      tags: ["apps_v1"]
+      x-kubernetes-action: "list"
+      x-kubernetes-group-version-kind:
+        group: "apps"
+        version: "v1"
+        kind: "Deployment"
+      responses:
+        200:
+          description: "OK"
+          schema:
+            $ref: "#/definitions/DeploymentList"
+    post:
+      tags: ["apps_v1"]
+      x-kubernetes-action: "post"
+      x-kubernetes-group-version-kind:
+        group: "apps"
+        version: "v1"
+        kind: "Deployment"
+      responses:
+        200:
+          description: "OK"
+          schema:
+            $ref: "#/definitions/Deployment"
+  /apis/extensions/v1beta1/deployments:
+    get:
+      tags: ["extensions_v1beta1"]
+      deprecated: true
+      x-kubernetes-action: "list"
+      responses:
+        200:
+          description: "OK"
+          schema:
+            $ref: "#/definitions/OldDeploymentList"
+definitions:
+  Deployment:
+    type: "object"
+  DeploymentList:
+    type: "object"
+    properties:
+      items:
+        type: "array"
+        items:
+          $ref: "#/definitions/Deployment"
+  OldDeploymentList:
+    type: "object"
+  Unused:
+    type: "object"
+`
+
+func TestFilterSpecByOperations(t *testing.T) {
+	var sp *spec.Swagger
+	if err := yaml.Unmarshal([]byte(operationFilterSpec), &sp); err != nil {
+		t.Fatal(err)
+	}
+	orig, _ := cloneSpec(sp)
+
+	type operation struct{ path, method string }
+	tcs := []struct {
+		name                string
+		predicate           OperationPredicate
+		expectedOperations  []operation
+		expectedDefinitions []string
+	}{
+		{
+			name:                "method",
+			predicate:           HasMethod("GET"),
+			expectedOperations:  []operation{{"/apis/apps/v1/deployments", "get"}, {"/apis/extensions/v1beta1/deployments", "get"}},
+			expectedDefinitions: []string{"Deployment", "DeploymentList", "OldDeploymentList", "Unused"},
+		},
+		{
+			name:                "not deprecated",
+			predicate:           Not(IsDeprecated()),
+			expectedOperations:  []operation{{"/apis/apps/v1/deployments", "get"}, {"/apis/apps/v1/deployments", "post"}},
+			expectedDefinitions: []string{"Deployment", "DeploymentList", "Unused"},
+		},
+		{
+			name:                "action",
+			predicate:           HasExtension("X-Kubernetes-Action", "post", "delete"),
+			expectedOperations:  []operation{{"/apis/apps/v1/deployments", "post"}},
+			expectedDefinitions: []string{"Deployment", "Unused"},
+		},
+		{
+			name:                "group version kind",
+			predicate:           HasGroupVersionKind(GroupVersionKind{Group: "apps", Kind: "Deployment"}),
+			expectedOperations:  []operation{{"/apis/apps/v1/deployments", "get"}, {"/apis/apps/v1/deployments", "post"}},
+			expectedDefinitions: []string{"Deployment", "DeploymentList", "Unused"},
+		},
+		{
+			name:                "tag or path",
+			predicate:           Or(And(HasTag("apps_v1"), HasMethod("post")), HasPathPrefix("/apis/extensions")),
+			expectedOperations:  []operation{{"/apis/apps/v1/deployments", "post"}, {"/apis/extensions/v1beta1/deployments", "get"}},
+			expectedDefinitions: []string{"Deployment", "OldDeploymentList", "Unused"},
+		},
+		{
+			name:                "none",
+			predicate:           HasExtension("x-unknown"),
+			expectedDefinitions: []string{"Unused"},
+		},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			filtered := FilterSpecByOperations(sp, tc.predicate)
+			var ops []operation
+			for path, pathItem := range filtered.Paths.Paths {
+				for _, m := range pathItemOperations(&pathItem) {
+					if *m.op != nil {
+						ops = append(ops, operation{path, m.method})
+					}
+				}
+			}
+			sort.Slice(ops, func(i, j int) bool {
+				return ops[i].path < ops[j].path || ops[i].path == ops[j].path && ops[i].method < ops[j].method
+			})
+			assert.Equal(t, tc.expectedOperations, ops)
+			assert.Equal(t, tc.expectedDefinitions, keys(filtered.Definitions))
+			assert.Equal(t, DebugSpec{orig}, DebugSpec{sp})
+		})
+	}
+}
@@ -0,0 +1,96 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aggregator
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/go-openapi/spec"
+)
+
+// operationIDs returns the non-empty operation IDs of the spec.
+func operationIDs(s *spec.Swagger) map[string]bool {
+	ids := map[string]bool{}
+	if s.Paths == nil {
+		return ids
+	}
+	for _, pathItem := range s.Paths.Paths {
+		for _, m := range pathItemOperations(&pathItem) {
+			if op := *m.op; op != nil && op.ID != "" {
+				ids[op.ID] = true
+			}
+		}
+	}
+	return ids
+}
+
+// resolveOperationIDConflicts finds the operations of source whose ID dest uses as well, and handles them as
+// configured by the options. It returns the source with the changed operation IDs, and records the conflicts in
+// the report. The source is not mutated.
+func resolveOperationIDConflicts(dest, source *spec.Swagger, opts *MergeOptions, report *MergeReport) (*spec.Swagger, error) {
+	destIDs := operationIDs(dest)
+	if len(destIDs) == 0 || source.Paths == nil {
+		return source, nil
+	}
+	usedIDs := operationIDs(source)
+	for id := range destIDs {
+		usedIDs[id] = true
+	}
+	newIDs := map[*spec.Operation]string{}
+	for _, path := range sortedMapKeys(source.Paths.Paths) {
+		pathItem := source.Paths.Paths[path]
+		for _, m := range pathItemOperations(&pathItem) {
+			op := *m.op
+			if op == nil || !destIDs[op.ID] {
+				continue
+			}
+			conflict := OperationIDConflict{PathOperation: PathOperation{Path: path, Method: m.method}, OperationID: op.ID}
+			switch opts.OperationIDConflicts {
+			case OperationIDConflictKeep:
+			case OperationIDConflictFail:
+				return nil, fmt.Errorf("unable to merge: duplicated operation ID %s of %s %s", op.ID, strings.ToUpper(m.method), path)
+			case OperationIDConflictPrefix, OperationIDConflictSuffix:
+				base := op.ID
+				if opts.OperationIDConflicts == OperationIDConflictPrefix && opts.SourceName != "" {
+					base = opts.SourceName + "_" + op.ID
+				}
+				conflict.NewOperationID = base
+				for i := 2; usedIDs[conflict.NewOperationID]; i++ {
+					conflict.NewOperationID = fmt.Sprintf("%s_v%d", base, i)
+				}
+				usedIDs[conflict.NewOperationID] = true
+				newIDs[op] = conflict.NewOperationID
+			default:
+				return nil, fmt.Errorf("unknown operation ID conflict policy %q", opts.OperationIDConflicts)
+			}
+			report.OperationIDConflicts = append(report.OperationIDConflicts, conflict)
+		}
+	}
+	if len(newIDs) == 0 {
+		return source, nil
+	}
+	return rewriteOperations(source, func(op *spec.Operation) *spec.Operation {
+		newID, found := newIDs[op]
+		if !found {
+			return op
+		}
+		renamed := *op
+		renamed.ID = newID
+		return &renamed
+	}), nil
+}
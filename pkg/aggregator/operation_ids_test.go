@@ -0,0 +1,99 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aggregator
+
+import (
+	"testing"
+
+	"github.com/ghodss/yaml"
+	"github.com/go-openapi/spec"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMergeSpecsOperationIDConflicts(t *testing.T) {
+	var dest, source *spec.Swagger
+	yaml.Unmarshal([]byte(`
+swagger: "2.0"
+paths:
+  /a:
+    get:
+      operationId: "getAPIResources"
+      responses:
+        200:
+          description: "OK"
+  /b:
+    get:
+      operationId: "metrics_getAPIResources"
+      responses:
+        200:
+          description: "OK"
+`), &dest)
+	yaml.Unmarshal([]byte(`
+swagger: "2.0"
+paths:
+  /apis/metrics:
+    get:
+      operationId: "getAPIResources"
+      responses:
+        200:
+          description: "OK"
+    post:
+      operationId: "createMetric"
+      responses:
+        200:
+          description: "OK"
+`), &source)
+
+	tcs := []struct {
+		policy      OperationIDConflictPolicy
+		newID       string
+		expectedErr string
+	}{
+		{OperationIDConflictKeep, "", ""},
+		{OperationIDConflictFail, "", "unable to merge: duplicated operation ID getAPIResources of GET /apis/metrics"},
+		{OperationIDConflictPrefix, "metrics_getAPIResources_v2", ""},
+		{OperationIDConflictSuffix, "getAPIResources_v2", ""},
+		{"Unknown", "", `unknown operation ID conflict policy "Unknown"`},
+	}
+	for _, tc := range tcs {
+		t.Run(string(tc.policy), func(t *testing.T) {
+			ast := assert.New(t)
+			merged, _ := cloneSpec(dest)
+			report, err := MergeSpecsWithOptions(merged, source, MergeOptions{SourceName: "metrics", OperationIDConflicts: tc.policy})
+			if tc.expectedErr != "" {
+				ast.EqualError(err, tc.expectedErr)
+				ast.Equal(DebugSpec{dest}, DebugSpec{merged})
+				return
+			}
+			if !ast.NoError(err) {
+				return
+			}
+			ast.Equal([]OperationIDConflict{{
+				PathOperation:  PathOperation{Path: "/apis/metrics", Method: "get"},
+				OperationID:    "getAPIResources",
+				NewOperationID: tc.newID,
+			}}, report.OperationIDConflicts)
+			expectedID := tc.newID
+			if expectedID == "" {
+				expectedID = "getAPIResources"
+			}
+			ast.Equal(expectedID, merged.Paths.Paths["/apis/metrics"].Get.ID)
+			ast.Equal("createMetric", merged.Paths.Paths["/apis/metrics"].Post.ID)
+			ast.Equal("getAPIResources", source.Paths.Paths["/apis/metrics"].Get.ID)
+		})
+	}
+}
@@ -0,0 +1,143 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aggregator
+
+import (
+	"reflect"
+
+	"github.com/go-openapi/spec"
+)
+
+// methodOperation is an operation field of a path item.
+type methodOperation struct {
+	// method is the lower case HTTP method.
+	method string
+	op     **spec.Operation
+}
+
+// pathItemOperations returns the operation fields of the path item.
+func pathItemOperations(pathItem *spec.PathItem) []methodOperation {
+	return []methodOperation{
+		{"get", &pathItem.Get},
+		{"put", &pathItem.Put},
+		{"post", &pathItem.Post},
+		{"delete", &pathItem.Delete},
+		{"options", &pathItem.Options},
+		{"head", &pathItem.Head},
+		{"patch", &pathItem.Patch},
+	}
+}
+
+// hasOperations returns true if the path item has at least one operation.
+func hasOperations(pathItem *spec.PathItem) bool {
+	for _, m := range pathItemOperations(pathItem) {
+		if *m.op != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// operationConflicts returns the sorted operations both dest and source have.
+func operationConflicts(dest, source *spec.Swagger) []PathOperation {
+	var conflicts []PathOperation
+	for path, sourceItem := range source.Paths.Paths {
+		destItem, found := dest.Paths.Paths[path]
+		if !found {
+			continue
+		}
+		destOps := pathItemOperations(&destItem)
+		for i, m := range pathItemOperations(&sourceItem) {
+			if *m.op != nil && *destOps[i].op != nil {
+				conflicts = append(conflicts, PathOperation{Path: path, Method: m.method})
+			}
+		}
+	}
+	sortPathOperations(conflicts)
+	return conflicts
+}
+
+// dropOperations removes the given operations from the spec, as well as path items left without operations,
+// and the definitions, shared parameters and shared responses no longer used. The input is not mutated.
+func dropOperations(s *spec.Swagger, drop []PathOperation) *spec.Swagger {
+	dropped := map[PathOperation]bool{}
+	for _, op := range drop {
+		dropped[op] = true
+	}
+	return filterPaths(s, func(path string, pathItem spec.PathItem) (spec.PathItem, bool) {
+		changed := false
+		for _, m := range pathItemOperations(&pathItem) {
+			if *m.op != nil && dropped[PathOperation{Path: path, Method: m.method}] {
+				*m.op = nil
+				changed = true
+			}
+		}
+		return pathItem, !changed || hasOperations(&pathItem)
+	})
+}
+
+// mergePathItems returns a path item with the operations of both path items, which must not have operations of
+// the same method. The other fields are taken from dest. If the path-level parameters differ, they are moved
+// into the operations. The inputs are not mutated.
+func mergePathItems(dest, source spec.PathItem) spec.PathItem {
+	ret := dest
+	sameParameters := reflect.DeepEqual(
+		sortedParameters(append([]spec.Parameter(nil), dest.Parameters...)),
+		sortedParameters(append([]spec.Parameter(nil), source.Parameters...)))
+	destOps, sourceOps, retOps := pathItemOperations(&dest), pathItemOperations(&source), pathItemOperations(&ret)
+	for i := range retOps {
+		switch {
+		case *sourceOps[i].op != nil && sameParameters:
+			*retOps[i].op = *sourceOps[i].op
+		case *sourceOps[i].op != nil:
+			*retOps[i].op = inheritParameters(*sourceOps[i].op, source.Parameters)
+		case *destOps[i].op != nil && !sameParameters:
+			*retOps[i].op = inheritParameters(*destOps[i].op, dest.Parameters)
+		}
+	}
+	if !sameParameters {
+		ret.Parameters = nil
+	}
+	return ret
+}
+
+// inheritParameters returns the operation with the given path-level parameters that it does not override
+// added to its own parameters. The input is not mutated.
+func inheritParameters(op *spec.Operation, pathParameters []spec.Parameter) *spec.Operation {
+	if len(pathParameters) == 0 {
+		return op
+	}
+	type parameterKey struct {
+		name, in, ref string
+	}
+	key := func(p *spec.Parameter) parameterKey {
+		return parameterKey{p.Name, p.In, p.Ref.String()}
+	}
+	overridden := map[parameterKey]bool{}
+	for i := range op.Parameters {
+		overridden[key(&op.Parameters[i])] = true
+	}
+	params := make([]spec.Parameter, 0, len(pathParameters)+len(op.Parameters))
+	for i := range pathParameters {
+		if !overridden[key(&pathParameters[i])] {
+			params = append(params, pathParameters[i])
+		}
+	}
+	ret := *op
+	ret.Parameters = append(params, op.Parameters...)
+	return &ret
+}
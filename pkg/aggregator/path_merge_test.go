@@ -0,0 +1,250 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aggregator
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/ghodss/yaml"
+	"github.com/go-openapi/spec"
+	"github.com/stretchr/testify/assert"
+)
+
+const mergeOperationsDest = `
+swagger: "2.0"
+paths:
+  /a:
+    parameters:
+    - name: "pretty"
+      in: "query"
+      type: "string"
+    get:
+      responses:
+        200:
+          description: "OK"
+          schema:
+            $ref: "#/definitions/Foo"
+definitions:
+  Foo:
+    type: "string"
+`
+
+func TestMergeSpecsMergeOperations(t *testing.T) {
+	var dest, source, expected *spec.Swagger
+	yaml.Unmarshal([]byte(mergeOperationsDest), &dest)
+	yaml.Unmarshal([]byte(`
+swagger: "2.0"
+paths:
+  /a:
+    parameters:
+    - name: "dryRun"
+      in: "query"
+      type: "string"
+    post:
+      parameters:
+      - name: "dryRun"
+        in: "query"
+        type: "boolean"
+      responses:
+        200:
+          description: "OK"
+          schema:
+            $ref: "#/definitions/Foo"
+    put:
+      responses:
+        200:
+          description: "OK"
+  /b:
+    get:
+      responses:
+        200:
+          description: "OK"
+definitions:
+  Foo:
+    type: "integer"
+`), &source)
+	yaml.Unmarshal([]byte(`
+swagger: "2.0"
+paths:
+  /a:
+    get:
+      parameters:
+      - name: "pretty"
+        in: "query"
+        type: "string"
+      responses:
+        200:
+          description: "OK"
+          schema:
+            $ref: "#/definitions/Foo"
+    post:
+      parameters:
+      - name: "dryRun"
+        in: "query"
+        type: "boolean"
+      responses:
+        200:
+          description: "OK"
+          schema:
+            $ref: "#/definitions/Foo_v2"
+    put:
+      parameters:
+      - name: "dryRun"
+        in: "query"
+        type: "string"
+      responses:
+        200:
+          description: "OK"
+  /b:
+    get:
+      responses:
+        200:
+          description: "OK"
+definitions:
+  Foo:
+    type: "string"
+  Foo_v2:
+    type: "integer"
+`), &expected)
+
+	ast := assert.New(t)
+	origSource, _ := cloneSpec(source)
+	_, err := MergeSpecsWithOptions(dest, source, MergeOptions{})
+	ast.EqualError(err, "unable to merge: duplicated path /a")
+
+	report, err := MergeSpecsWithOptions(dest, source, MergeOptions{MergeOperations: true})
+	if !ast.NoError(err) {
+		return
+	}
+	ast.Equal(DebugSpec{expected}, DebugSpec{dest})
+	ast.Equal(DebugSpec{origSource}, DebugSpec{source})
+	ast.Equal([]string{"/b"}, report.AddedPaths)
+	ast.Equal([]PathOperation{{"/a", "post"}, {"/a", "put"}}, report.AddedOperations)
+}
+
+func TestMergeSpecsMergeOperationsConflict(t *testing.T) {
+	var dest, source, expected *spec.Swagger
+	yaml.Unmarshal([]byte(mergeOperationsDest), &dest)
+	yaml.Unmarshal([]byte(`
+swagger: "2.0"
+paths:
+  /a:
+    parameters:
+    - name: "pretty"
+      in: "query"
+      type: "string"
+    get:
+      responses:
+        200:
+          description: "OK"
+          schema:
+            $ref: "#/definitions/Bar"
+    delete:
+      responses:
+        200:
+          description: "OK"
+definitions:
+  Bar:
+    type: "string"
+`), &source)
+	yaml.Unmarshal([]byte(`
+swagger: "2.0"
+paths:
+  /a:
+    parameters:
+    - name: "pretty"
+      in: "query"
+      type: "string"
+    get:
+      responses:
+        200:
+          description: "OK"
+          schema:
+            $ref: "#/definitions/Foo"
+    delete:
+      responses:
+        200:
+          description: "OK"
+definitions:
+  Foo:
+    type: "string"
+`), &expected)
+
+	ast := assert.New(t)
+	_, err := MergeSpecsWithOptions(dest, source, MergeOptions{MergeOperations: true})
+	ast.EqualError(err, "unable to merge: duplicated operation GET /a")
+
+	report, err := MergeSpecsWithOptions(dest, source, MergeOptions{MergeOperations: true, IgnorePathConflicts: true})
+	if !ast.NoError(err) {
+		return
+	}
+	ast.Equal(DebugSpec{expected}, DebugSpec{dest})
+	ast.Equal([]PathOperation{{"/a", "get"}}, report.SkippedOperations)
+	ast.Equal([]PathOperation{{"/a", "delete"}}, report.AddedOperations)
+}
+
+func TestIncrementalAggregatorMergeOperations(t *testing.T) {
+	ast := assert.New(t)
+	var base, a, b *spec.Swagger
+	yaml.Unmarshal([]byte(mergeOperationsDest), &base)
+	yaml.Unmarshal([]byte(`
+swagger: "2.0"
+paths:
+  /a:
+    post:
+      responses:
+        200:
+          description: "OK"
+`), &a)
+	yaml.Unmarshal([]byte(`
+swagger: "2.0"
+paths:
+  /a:
+    parameters:
+    - name: "dryRun"
+      in: "query"
+      type: "string"
+    put:
+      responses:
+        200:
+          description: "OK"
+`), &b)
+	opts := MergeOptions{MergeOperations: true}
+	agg, err := NewIncrementalAggregator(base, opts)
+	if !ast.NoError(err) {
+		return
+	}
+	for _, s := range []struct {
+		name string
+		spec *spec.Swagger
+	}{{"a", a}, {"b", b}} {
+		_, err := agg.AddOrUpdateSource(s.name, s.spec)
+		ast.NoError(err)
+	}
+	ast.NoError(agg.RemoveSource("a"))
+
+	expected, _ := cloneSpec(base)
+	_, err = MergeSpecsWithOptions(expected, b, opts)
+	ast.NoError(err)
+	expectedJSON, _ := json.Marshal(expected)
+	actualJSON, _ := json.Marshal(agg.Spec())
+	ast.Equal(string(expectedJSON), string(actualJSON))
+
+	ast.NoError(agg.RemoveSource("b"))
+	ast.Equal(base, agg.Spec())
+}
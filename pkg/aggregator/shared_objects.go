@@ -0,0 +1,436 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aggregator
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+
+	"github.com/go-openapi/spec"
+)
+
+// sharedObjects gives uniform access to one section of named objects of a spec, e.g. the definitions,
+// so that merging can resolve conflicts the same way for all sections.
+type sharedObjects struct {
+	// kind names the objects in reports.
+	kind ObjectKind
+	// conflictName names the objects in conflict errors.
+	conflictName string
+	// names returns the sorted names of the objects of the spec.
+	names func(s *spec.Swagger) []string
+	// get returns the named object of the spec.
+	get func(s *spec.Swagger, name string) interface{}
+	// has returns true if the spec has an object of the given name.
+	has func(s *spec.Swagger, name string) bool
+	// equal returns true if the object destName of dest is equivalent to the object sourceName of source,
+	// as configured by the options.
+	equal func(opts *MergeOptions, dest, source *spec.Swagger, destName, sourceName string) bool
+	// rename renames objects and all references to them, without mutating the input.
+	rename func(s *spec.Swagger, renames map[string]string) *spec.Swagger
+	// add copies the named object of source to dest.
+	add func(dest, source *spec.Swagger, name string)
+	// remove deletes the named object from the spec.
+	remove func(s *spec.Swagger, name string)
+}
+
+// sharedObjectSections lists the sections merged by mergeSpecs, in the order conflicts are resolved.
+var sharedObjectSections = []*sharedObjects{
+	{
+		kind:         DefinitionKind,
+		conflictName: "model",
+		names: func(s *spec.Swagger) []string {
+			return sortedMapKeys(s.Definitions)
+		},
+		get: func(s *spec.Swagger, name string) interface{} {
+			return s.Definitions[name]
+		},
+		has: func(s *spec.Swagger, name string) bool {
+			_, found := s.Definitions[name]
+			return found
+		},
+		equal: func(opts *MergeOptions, dest, source *spec.Swagger, destName, sourceName string) bool {
+			destDef, sourceDef := dest.Definitions[destName], source.Definitions[sourceName]
+			return opts.definitionEquivalence()(&destDef, &sourceDef)
+		},
+		rename: renameDefinition,
+		add: func(dest, source *spec.Swagger, name string) {
+			if dest.Definitions == nil {
+				dest.Definitions = spec.Definitions{}
+			}
+			dest.Definitions[name] = source.Definitions[name]
+		},
+		remove: func(s *spec.Swagger, name string) {
+			delete(s.Definitions, name)
+			if len(s.Definitions) == 0 {
+				s.Definitions = nil
+			}
+		},
+	},
+	{
+		kind:         ParameterKind,
+		conflictName: "parameter",
+		names: func(s *spec.Swagger) []string {
+			return sortedMapKeys(s.Parameters)
+		},
+		get: func(s *spec.Swagger, name string) interface{} {
+			return s.Parameters[name]
+		},
+		has: func(s *spec.Swagger, name string) bool {
+			_, found := s.Parameters[name]
+			return found
+		},
+		equal: func(_ *MergeOptions, dest, source *spec.Swagger, destName, sourceName string) bool {
+			return reflect.DeepEqual(dest.Parameters[destName], source.Parameters[sourceName])
+		},
+		rename: func(s *spec.Swagger, renames map[string]string) *spec.Swagger {
+			return renameReferences(s, parameterPrefix, renames)
+		},
+		add: func(dest, source *spec.Swagger, name string) {
+			if dest.Parameters == nil {
+				dest.Parameters = map[string]spec.Parameter{}
+			}
+			dest.Parameters[name] = source.Parameters[name]
+		},
+		remove: func(s *spec.Swagger, name string) {
+			delete(s.Parameters, name)
+			if len(s.Parameters) == 0 {
+				s.Parameters = nil
+			}
+		},
+	},
+	{
+		kind:         ResponseKind,
+		conflictName: "response",
+		names: func(s *spec.Swagger) []string {
+			return sortedMapKeys(s.Responses)
+		},
+		get: func(s *spec.Swagger, name string) interface{} {
+			return s.Responses[name]
+		},
+		has: func(s *spec.Swagger, name string) bool {
+			_, found := s.Responses[name]
+			return found
+		},
+		equal: func(_ *MergeOptions, dest, source *spec.Swagger, destName, sourceName string) bool {
+			return reflect.DeepEqual(dest.Responses[destName], source.Responses[sourceName])
+		},
+		rename: func(s *spec.Swagger, renames map[string]string) *spec.Swagger {
+			return renameReferences(s, responsePrefix, renames)
+		},
+		add: func(dest, source *spec.Swagger, name string) {
+			if dest.Responses == nil {
+				dest.Responses = map[string]spec.Response{}
+			}
+			dest.Responses[name] = source.Responses[name]
+		},
+		remove: func(s *spec.Swagger, name string) {
+			delete(s.Responses, name)
+			if len(s.Responses) == 0 {
+				s.Responses = nil
+			}
+		},
+	},
+	{
+		kind:         SecurityDefinitionKind,
+		conflictName: "security definition",
+		names: func(s *spec.Swagger) []string {
+			return sortedMapKeys(s.SecurityDefinitions)
+		},
+		get: func(s *spec.Swagger, name string) interface{} {
+			return s.SecurityDefinitions[name]
+		},
+		has: func(s *spec.Swagger, name string) bool {
+			_, found := s.SecurityDefinitions[name]
+			return found
+		},
+		equal: func(_ *MergeOptions, dest, source *spec.Swagger, destName, sourceName string) bool {
+			return reflect.DeepEqual(dest.SecurityDefinitions[destName], source.SecurityDefinitions[sourceName])
+		},
+		rename: renameSecurityDefinitions,
+		add: func(dest, source *spec.Swagger, name string) {
+			if dest.SecurityDefinitions == nil {
+				dest.SecurityDefinitions = spec.SecurityDefinitions{}
+			}
+			dest.SecurityDefinitions[name] = source.SecurityDefinitions[name]
+		},
+		remove: func(s *spec.Swagger, name string) {
+			delete(s.SecurityDefinitions, name)
+			if len(s.SecurityDefinitions) == 0 {
+				s.SecurityDefinitions = nil
+			}
+		},
+	},
+	{
+		kind:         TagKind,
+		conflictName: "tag",
+		names: func(s *spec.Swagger) []string {
+			names := make([]string, len(s.Tags))
+			for i, t := range s.Tags {
+				names[i] = t.Name
+			}
+			sort.Strings(names)
+			return names
+		},
+		get: func(s *spec.Swagger, name string) interface{} {
+			return s.Tags[findTag(s.Tags, name)]
+		},
+		has: func(s *spec.Swagger, name string) bool {
+			return findTag(s.Tags, name) >= 0
+		},
+		// Tags are identified by their name only. Tags of the same name are the same tag, which keeps the
+		// description and external docs of dest.
+		equal: func(_ *MergeOptions, _, _ *spec.Swagger, destName, sourceName string) bool {
+			return destName == sourceName
+		},
+		rename: renameTags,
+		add: func(dest, source *spec.Swagger, name string) {
+			dest.Tags = append(dest.Tags, source.Tags[findTag(source.Tags, name)])
+		},
+		remove: func(s *spec.Swagger, name string) {
+			if i := findTag(s.Tags, name); i >= 0 {
+				s.Tags = append(s.Tags[:i:i], s.Tags[i+1:]...)
+			}
+			if len(s.Tags) == 0 {
+				s.Tags = nil
+			}
+		},
+	},
+}
+
+// resolveConflicts renames the objects of source that conflict with different objects of the same name in dest,
+// using the ConflictRenamer of the options, or fails if FailOnConflicts is set. Objects equal to the one of the
+// same name in dest, or to one renamed in an earlier merge, reuse that name. It returns the renamed source and
+// the renames, and records the renames in the report. The source is not mutated.
+func resolveConflicts(dest, source *spec.Swagger, objects *sharedObjects, opts *MergeOptions, report *MergeReport) (*spec.Swagger, map[string]string, error) {
+	sourceNames := objects.names(source)
+	conflicts := false
+	for _, k := range sourceNames {
+		if objects.has(dest, k) && !objects.equal(opts, dest, source, k, k) {
+			if opts.FailOnConflicts {
+				return nil, nil, fmt.Errorf("%s name conflict in merging OpenAPI spec: %s", objects.conflictName, k)
+			}
+			conflicts = true
+			break
+		}
+	}
+	if !conflicts {
+		return source, nil, nil
+	}
+
+	usedNames := map[string]bool{}
+	for _, k := range objects.names(dest) {
+		usedNames[k] = true
+	}
+	renames := map[string]string{}
+
+	renamer := opts.ConflictRenamer
+	if renamer == nil {
+		renamer = VersionSuffixRenamer()
+	}
+	for _, k := range sourceNames {
+		if !usedNames[k] {
+			continue
+		}
+		// Reuse object if they are exactly the same.
+		if objects.has(dest, k) && objects.equal(opts, dest, source, k, k) {
+			continue
+		}
+
+		conflict := Conflict{Kind: objects.kind, Name: k, Source: opts.SourceName, Object: objects.get(source, k)}
+		newName, reason, err := findConflictName(dest, source, objects, opts, renamer, conflict, usedNames)
+		if err != nil {
+			return nil, nil, err
+		}
+		renames[k] = newName
+		usedNames[newName] = true
+		report.Renamed = append(report.Renamed, MergedObject{Kind: objects.kind, Name: k, NewName: newName, Reason: reason})
+	}
+	return objects.rename(source, renames), renames, nil
+}
+
+// maxConflictRenameAttempts bounds the candidates asked from a ConflictRenamer for a single conflict.
+const maxConflictRenameAttempts = 1000
+
+// findConflictName asks the renamer for candidate names until one is either free, or used by an identical
+// object in dest, which is then reused.
+func findConflictName(dest, source *spec.Swagger, objects *sharedObjects, opts *MergeOptions, renamer ConflictRenamer, conflict Conflict, usedNames map[string]bool) (string, RenameReason, error) {
+	for attempt := 0; attempt < maxConflictRenameAttempts; attempt++ {
+		newName := renamer.RenameConflict(conflict, attempt)
+		if newName == "" || newName == conflict.Name {
+			continue
+		}
+		if objects.has(dest, newName) {
+			if objects.equal(opts, dest, source, newName, conflict.Name) {
+				return newName, RenameReasonReused, nil
+			}
+			continue
+		}
+		if usedNames[newName] || objects.has(source, newName) {
+			continue
+		}
+		return newName, RenameReasonConflict, nil
+	}
+	return "", "", fmt.Errorf("unable to merge: no unique name found for conflicting %s %s", conflict.Kind, conflict.Name)
+}
+
+// findTag returns the index of the named tag, or -1.
+func findTag(tags []spec.Tag, name string) int {
+	for i := range tags {
+		if tags[i].Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// renameSecurityDefinitions renames security definitions and all security requirements using them,
+// without mutating the input. The output might share data structures with the input.
+func renameSecurityDefinitions(s *spec.Swagger, renames map[string]string) *spec.Swagger {
+	ret := &spec.Swagger{}
+	*ret = *s
+	ret.SecurityDefinitions = make(spec.SecurityDefinitions, len(s.SecurityDefinitions))
+	for k, v := range s.SecurityDefinitions {
+		if newName, found := renames[k]; found {
+			k = newName
+		}
+		ret.SecurityDefinitions[k] = v
+	}
+	renameRequirements := func(requirements []map[string][]string) ([]map[string][]string, bool) {
+		changed := false
+		renamed := make([]map[string][]string, len(requirements))
+		for i, requirement := range requirements {
+			renamed[i] = make(map[string][]string, len(requirement))
+			for k, v := range requirement {
+				if newName, found := renames[k]; found {
+					k = newName
+					changed = true
+				}
+				renamed[i][k] = v
+			}
+		}
+		if !changed {
+			return requirements, false
+		}
+		return renamed, true
+	}
+	ret.Security, _ = renameRequirements(s.Security)
+	return rewriteOperations(ret, func(op *spec.Operation) *spec.Operation {
+		security, changed := renameRequirements(op.Security)
+		if !changed {
+			return op
+		}
+		renamed := *op
+		renamed.Security = security
+		return &renamed
+	})
+}
+
+// renameTags renames tags and their uses by operations, without mutating the input.
+// The output might share data structures with the input.
+func renameTags(s *spec.Swagger, renames map[string]string) *spec.Swagger {
+	ret := &spec.Swagger{}
+	*ret = *s
+	ret.Tags = make([]spec.Tag, len(s.Tags))
+	for i, t := range s.Tags {
+		if newName, found := renames[t.Name]; found {
+			t.Name = newName
+		}
+		ret.Tags[i] = t
+	}
+	return rewriteOperations(ret, func(op *spec.Operation) *spec.Operation {
+		var tags []string
+		for i, t := range op.Tags {
+			if newName, found := renames[t]; found {
+				if tags == nil {
+					tags = append([]string(nil), op.Tags...)
+				}
+				tags[i] = newName
+			}
+		}
+		if tags == nil {
+			return op
+		}
+		renamed := *op
+		renamed.Tags = tags
+		return &renamed
+	})
+}
+
+// mergeDefaultSecurity makes sure the operations of source keep their default security requirements when
+// merged into dest. If dest has neither paths nor default security requirements, it adopts the ones of source.
+// The default requirements of a merged spec are thus the ones of the first merged source that has any, and
+// depend on the merge order, while the operations keep the requirements they have in their own spec.
+// Otherwise, if the default requirements differ, they are copied into the source operations relying on them.
+// Source operations without any requirement inherit the ones of dest, as an empty list of requirements
+// cannot be serialized. The source is not mutated.
+func mergeDefaultSecurity(dest, source *spec.Swagger) *spec.Swagger {
+	if source.Security == nil || reflect.DeepEqual(dest.Security, source.Security) {
+		return source
+	}
+	if dest.Security == nil && (dest.Paths == nil || len(dest.Paths.Paths) == 0) {
+		dest.Security = source.Security
+		return source
+	}
+	return rewriteOperations(source, func(op *spec.Operation) *spec.Operation {
+		if op.Security != nil {
+			return op
+		}
+		withSecurity := *op
+		withSecurity.Security = source.Security
+		return &withSecurity
+	})
+}
+
+// rewriteOperations calls f on all operations of the spec, and returns a spec with the operations returned
+// by f. f must return its input if it does not change the operation, and must not mutate its input.
+// The spec is not mutated, the output might share data structures with it.
+func rewriteOperations(s *spec.Swagger, f func(op *spec.Operation) *spec.Operation) *spec.Swagger {
+	if s.Paths == nil {
+		return s
+	}
+	var paths map[string]spec.PathItem
+	for k, pathItem := range s.Paths.Paths {
+		changed := false
+		for _, op := range []**spec.Operation{&pathItem.Get, &pathItem.Put, &pathItem.Post, &pathItem.Delete, &pathItem.Options, &pathItem.Head, &pathItem.Patch} {
+			if *op == nil {
+				continue
+			}
+			if rewritten := f(*op); rewritten != *op {
+				*op = rewritten
+				changed = true
+			}
+		}
+		if !changed {
+			continue
+		}
+		if paths == nil {
+			paths = make(map[string]spec.PathItem, len(s.Paths.Paths))
+			for k2, v2 := range s.Paths.Paths {
+				paths[k2] = v2
+			}
+		}
+		paths[k] = pathItem
+	}
+	if paths == nil {
+		return s
+	}
+	ret := &spec.Swagger{}
+	*ret = *s
+	ret.Paths = &spec.Paths{VendorExtensible: s.Paths.VendorExtensible, Paths: paths}
+	return ret
+}
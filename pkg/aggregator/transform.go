@@ -0,0 +1,282 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aggregator
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/go-openapi/spec"
+)
+
+// SpecTransformer transforms an OpenAPI spec. Transformers do not mutate their input, but the output
+// might share data structures with the input. Every SpecTransformer can be used as
+// common.Config.PostProcessSpec.
+type SpecTransformer func(*spec.Swagger) (*spec.Swagger, error)
+
+// ChainTransformers returns a SpecTransformer that applies the given transformers in order.
+func ChainTransformers(transformers ...SpecTransformer) SpecTransformer {
+	return func(sp *spec.Swagger) (*spec.Swagger, error) {
+		var err error
+		for _, t := range transformers {
+			if sp, err = t(sp); err != nil {
+				return nil, err
+			}
+		}
+		return sp, nil
+	}
+}
+
+// StripDescriptions returns a SpecTransformer removing the descriptions of schemas, parameters and operations.
+// Response descriptions are kept, as they are required by OpenAPI.
+func StripDescriptions() SpecTransformer {
+	return visitingTransformer(&SpecVisitor{
+		Schema:    func(_ string, s *spec.Schema) { s.Description = "" },
+		Parameter: func(_ string, p *spec.Parameter) { p.Description = "" },
+		Operation: func(_ string, op *spec.Operation) { op.Description = "" },
+	})
+}
+
+// DropExtensions returns a SpecTransformer removing all vendor extensions whose key starts with one of the
+// given prefixes (e.g. "x-kubernetes-"), ignoring case. Use "x-" to remove all vendor extensions.
+func DropExtensions(prefixes ...string) SpecTransformer {
+	lowerPrefixes := make([]string, len(prefixes))
+	for i := range prefixes {
+		lowerPrefixes[i] = strings.ToLower(prefixes[i])
+	}
+	return visitingTransformer(&SpecVisitor{
+		Extensible: func(_ string, e *spec.VendorExtensible) {
+			for k := range e.Extensions {
+				for _, p := range lowerPrefixes {
+					if strings.HasPrefix(strings.ToLower(k), p) {
+						delete(e.Extensions, k)
+						break
+					}
+				}
+			}
+			if len(e.Extensions) == 0 {
+				e.Extensions = nil
+			}
+		},
+	})
+}
+
+// SortAndNormalize returns a SpecTransformer bringing order-insensitive lists into a canonical order and
+// removing empty lists and maps. It sorts and deduplicates required properties and schemes, sorts parameters
+// by name and location, and sorts the top-level tags by name. Operation tags are only deduplicated, because
+// their order matters: the first tag usually determines where documentation tools list an operation.
+func SortAndNormalize() SpecTransformer {
+	transform := visitingTransformer(&SpecVisitor{
+		Extensible: func(_ string, e *spec.VendorExtensible) {
+			if len(e.Extensions) == 0 {
+				e.Extensions = nil
+			}
+		},
+		Schema: func(_ string, s *spec.Schema) {
+			s.Required = sortedUnique(s.Required)
+			if len(s.Properties) == 0 {
+				s.Properties = nil
+			}
+		},
+		Operation: func(_ string, op *spec.Operation) {
+			op.Tags = unique(op.Tags)
+			op.Schemes = sortedUnique(op.Schemes)
+			op.Parameters = sortedParameters(op.Parameters)
+		},
+		PathItem: func(_ string, pathItem *spec.PathItem) {
+			pathItem.Parameters = sortedParameters(pathItem.Parameters)
+		},
+	})
+	return func(sp *spec.Swagger) (*spec.Swagger, error) {
+		ret, err := transform(sp)
+		if err != nil {
+			return nil, err
+		}
+		ret.Schemes = sortedUnique(ret.Schemes)
+		sort.SliceStable(ret.Tags, func(i, j int) bool { return ret.Tags[i].Name < ret.Tags[j].Name })
+		if len(ret.Tags) == 0 {
+			ret.Tags = nil
+		}
+		return ret, nil
+	}
+}
+
+// RemoveUnusedDefinitions returns a SpecTransformer removing all definitions that are not reachable from
+// any path. Note that specs without paths lose all their definitions.
+func RemoveUnusedDefinitions() SpecTransformer {
+	return func(sp *spec.Swagger) (*spec.Swagger, error) {
+		usedDefinitions := usedDefinitionForSpec(sp)
+		ret := *sp
+		ret.Definitions = make(spec.Definitions, len(usedDefinitions))
+		for k, v := range sp.Definitions {
+			if usedDefinitions[k] {
+				ret.Definitions[k] = v
+			}
+		}
+		return &ret, nil
+	}
+}
+
+// InlineTrivialRefs returns a SpecTransformer replacing references to trivial definitions by the
+// definition itself. A definition is trivial if it is a primitive type, without properties or
+// sub-schemas. References to definitions that only reference another definition are replaced by
+// references to that definition, or by its schema if it is trivial. The inlined definitions are kept,
+// chain RemoveUnusedDefinitions to remove them.
+func InlineTrivialRefs() SpecTransformer {
+	return func(sp *spec.Swagger) (*spec.Swagger, error) {
+		replacements := map[string]spec.Schema{}
+		for name := range sp.Definitions {
+			if s, ok := trivialReplacement(sp.Definitions, name, map[string]bool{}); ok {
+				replacements[definitionPrefix+name] = s
+			}
+		}
+		if len(replacements) == 0 {
+			return sp, nil
+		}
+		return visitingTransformer(&SpecVisitor{
+			Schema: func(_ string, s *spec.Schema) {
+				if r, ok := replacements[s.Ref.String()]; ok {
+					*s = r
+				}
+			},
+		})(sp)
+	}
+}
+
+// trivialReplacement returns the schema replacing references to the given definition, if it is trivial or an
+// alias of another definition. visited detects cycles of aliases, which are never replaced.
+func trivialReplacement(definitions spec.Definitions, name string, visited map[string]bool) (spec.Schema, bool) {
+	def, ok := definitions[name]
+	if !ok || visited[name] {
+		return spec.Schema{}, false
+	}
+	visited[name] = true
+	if ref := def.Ref.String(); ref != "" {
+		if !strings.HasPrefix(ref, definitionPrefix) {
+			return spec.Schema{}, false
+		}
+		target := ref[len(definitionPrefix):]
+		if _, ok := definitions[target]; !ok || visited[target] {
+			// Dangling references and cycles of aliases are kept.
+			return spec.Schema{}, false
+		}
+		if s, ok := trivialReplacement(definitions, target, visited); ok {
+			return s, true
+		}
+		if isDefinitionAlias(definitions[target]) {
+			// target is an alias that cannot be resolved.
+			return spec.Schema{}, false
+		}
+		return spec.Schema{SchemaProps: spec.SchemaProps{Ref: def.Ref}}, true
+	}
+	if len(def.Type) != 1 || def.Type[0] == "object" || def.Type[0] == "array" ||
+		len(def.Properties) > 0 || len(def.PatternProperties) > 0 || def.AdditionalProperties != nil ||
+		def.Items != nil || def.AdditionalItems != nil ||
+		len(def.AllOf) > 0 || len(def.AnyOf) > 0 || len(def.OneOf) > 0 || def.Not != nil {
+		return spec.Schema{}, false
+	}
+	return def, true
+}
+
+// isDefinitionAlias returns true if the definition references another definition.
+func isDefinitionAlias(def spec.Schema) bool {
+	return strings.HasPrefix(def.Ref.String(), definitionPrefix)
+}
+
+// RenameDefinitions returns a SpecTransformer renaming definitions and all references to them, given a map
+// from old to new names. Renaming a definition to the name of another definition that is not renamed as well
+// is an error.
+func RenameDefinitions(renames map[string]string) SpecTransformer {
+	return func(sp *spec.Swagger) (*spec.Swagger, error) {
+		froms := make([]string, 0, len(renames))
+		for from := range renames {
+			froms = append(froms, from)
+		}
+		sort.Strings(froms)
+		targets := map[string]string{}
+		for _, from := range froms {
+			to := renames[from]
+			if other, ok := targets[to]; ok {
+				return nil, fmt.Errorf("cannot rename both %q and %q to %q", other, from, to)
+			}
+			targets[to] = from
+			if _, exists := sp.Definitions[to]; exists {
+				if _, renamed := renames[to]; !renamed {
+					return nil, fmt.Errorf("cannot rename definition %q to %q: name already in use", from, to)
+				}
+			}
+		}
+		return renameDefinition(sp, renames), nil
+	}
+}
+
+// visitingTransformer returns a SpecTransformer rewriting the spec with the visitor.
+func visitingTransformer(v *SpecVisitor) SpecTransformer {
+	return func(sp *spec.Swagger) (*spec.Swagger, error) {
+		return RewriteSpec(sp, v)
+	}
+}
+
+// sortedUnique sorts and deduplicates the list in place. Empty lists become nil.
+func sortedUnique(l []string) []string {
+	if len(l) == 0 {
+		return nil
+	}
+	sort.Strings(l)
+	ret := l[:1]
+	for _, s := range l[1:] {
+		if s != ret[len(ret)-1] {
+			ret = append(ret, s)
+		}
+	}
+	return ret
+}
+
+// unique removes later occurrences of duplicate strings, keeping the order. Empty lists become nil.
+func unique(l []string) []string {
+	if len(l) == 0 {
+		return nil
+	}
+	seen := make(map[string]bool, len(l))
+	ret := make([]string, 0, len(l))
+	for _, s := range l {
+		if !seen[s] {
+			seen[s] = true
+			ret = append(ret, s)
+		}
+	}
+	return ret
+}
+
+// sortedParameters sorts the parameters in place by name, location and reference. Empty lists become nil.
+func sortedParameters(params []spec.Parameter) []spec.Parameter {
+	if len(params) == 0 {
+		return nil
+	}
+	sort.SliceStable(params, func(i, j int) bool {
+		a, b := params[i], params[j]
+		if a.Name != b.Name {
+			return a.Name < b.Name
+		}
+		if a.In != b.In {
+			return a.In < b.In
+		}
+		return a.Ref.String() < b.Ref.String()
+	})
+	return params
+}
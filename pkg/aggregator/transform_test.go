@@ -0,0 +1,223 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aggregator
+
+import (
+	"testing"
+
+	"github.com/ghodss/yaml"
+	"github.com/go-openapi/spec"
+	"github.com/stretchr/testify/assert"
+)
+
+const transformTestSpec = `
+swagger: "2.0"
+tags:
+- name: "z"
+- name: "a"
+paths:
+  /test:
+    x-kubernetes-group: "test"
+    post:
+      tags:
+      - "test"
+      - "a"
+      - "test"
+      description: "Add test"
+      operationId: "addTest"
+      x-kubernetes-action: "post"
+      x-other: "other"
+      parameters:
+      - in: "query"
+        name: "pretty"
+        description: "pretty print"
+        type: "string"
+      - in: "body"
+        name: "body"
+        description: "test object"
+        required: true
+        schema:
+          $ref: "#/definitions/Test"
+      responses:
+        200:
+          description: "OK"
+          schema:
+            $ref: "#/definitions/Time"
+definitions:
+  Test:
+    type: "object"
+    description: "Test object"
+    x-kubernetes-group-version-kind: "test"
+    required:
+    - "status"
+    - "id"
+    properties:
+      status:
+        type: "string"
+        description: "Status"
+      created:
+        $ref: "#/definitions/Time"
+      updated:
+        $ref: "#/definitions/UpdateTime"
+  Time:
+    type: "string"
+    format: "date-time"
+  UpdateTime:
+    $ref: "#/definitions/Time"
+  Unused:
+    type: "object"
+`
+
+func loadTransformTestSpec(t *testing.T) *spec.Swagger {
+	var sp *spec.Swagger
+	if err := yaml.Unmarshal([]byte(transformTestSpec), &sp); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return sp
+}
+
+func TestStripDescriptions(t *testing.T) {
+	sp := loadTransformTestSpec(t)
+	orig, _ := cloneSpec(sp)
+	ret, err := StripDescriptions()(sp)
+	ast := assert.New(t)
+	if !ast.NoError(err) {
+		return
+	}
+	ast.Equal(DebugSpec{orig}, DebugSpec{sp}, "unexpected mutation of input")
+	op := ret.Paths.Paths["/test"].Post
+	ast.Empty(op.Description)
+	ast.Empty(op.Parameters[0].Description)
+	ast.Equal("OK", op.Responses.StatusCodeResponses[200].Description)
+	ast.Empty(ret.Definitions["Test"].Description)
+	ast.Empty(ret.Definitions["Test"].Properties["status"].Description)
+}
+
+func TestDropExtensions(t *testing.T) {
+	sp := loadTransformTestSpec(t)
+	orig, _ := cloneSpec(sp)
+	ret, err := DropExtensions("X-Kubernetes-")(sp)
+	ast := assert.New(t)
+	if !ast.NoError(err) {
+		return
+	}
+	ast.Equal(DebugSpec{orig}, DebugSpec{sp}, "unexpected mutation of input")
+	ast.Nil(ret.Paths.Paths["/test"].Extensions)
+	ast.Equal(spec.Extensions{"x-other": "other"}, ret.Paths.Paths["/test"].Post.Extensions)
+	ast.Nil(ret.Definitions["Test"].Extensions)
+}
+
+func TestSortAndNormalize(t *testing.T) {
+	sp := loadTransformTestSpec(t)
+	ret, err := SortAndNormalize()(sp)
+	ast := assert.New(t)
+	if !ast.NoError(err) {
+		return
+	}
+	ast.Equal([]string{"z", "a"}, []string{sp.Tags[0].Name, sp.Tags[1].Name}, "unexpected mutation of input")
+	ast.Equal([]string{"a", "z"}, []string{ret.Tags[0].Name, ret.Tags[1].Name})
+	op := ret.Paths.Paths["/test"].Post
+	ast.Equal([]string{"test", "a"}, op.Tags)
+	ast.Equal([]string{"body", "pretty"}, []string{op.Parameters[0].Name, op.Parameters[1].Name})
+	ast.Equal([]string{"id", "status"}, ret.Definitions["Test"].Required)
+
+	again, err := SortAndNormalize()(ret)
+	if ast.NoError(err) {
+		ast.Equal(DebugSpec{ret}, DebugSpec{again})
+	}
+}
+
+func TestRemoveUnusedDefinitions(t *testing.T) {
+	sp := loadTransformTestSpec(t)
+	ret, err := RemoveUnusedDefinitions()(sp)
+	ast := assert.New(t)
+	if !ast.NoError(err) {
+		return
+	}
+	ast.Contains(sp.Definitions, "Unused", "unexpected mutation of input")
+	ast.Len(ret.Definitions, 3)
+	ast.NotContains(ret.Definitions, "Unused")
+}
+
+func TestInlineTrivialRefs(t *testing.T) {
+	sp := loadTransformTestSpec(t)
+	orig, _ := cloneSpec(sp)
+	ret, err := ChainTransformers(InlineTrivialRefs(), RemoveUnusedDefinitions())(sp)
+	ast := assert.New(t)
+	if !ast.NoError(err) {
+		return
+	}
+	ast.Equal(DebugSpec{orig}, DebugSpec{sp}, "unexpected mutation of input")
+
+	var expected *spec.Schema
+	yaml.Unmarshal([]byte(`
+type: "string"
+format: "date-time"
+`), &expected)
+	ast.Equal(*expected, *ret.Paths.Paths["/test"].Post.Responses.StatusCodeResponses[200].Schema)
+	ast.Equal(*expected, ret.Definitions["Test"].Properties["created"])
+	ast.Equal(*expected, ret.Definitions["Test"].Properties["updated"])
+	ast.Equal([]string{"Test"}, keys(ret.Definitions))
+}
+
+func TestInlineTrivialRefsCycle(t *testing.T) {
+	var sp *spec.Swagger
+	yaml.Unmarshal([]byte(`
+swagger: "2.0"
+definitions:
+  A:
+    $ref: "#/definitions/B"
+  B:
+    $ref: "#/definitions/A"
+  C:
+    $ref: "#/definitions/A"
+`), &sp)
+	ret, err := InlineTrivialRefs()(sp)
+	ast := assert.New(t)
+	if ast.NoError(err) {
+		ast.Equal(DebugSpec{sp}, DebugSpec{ret})
+	}
+}
+
+func TestRenameDefinitions(t *testing.T) {
+	sp := loadTransformTestSpec(t)
+	ret, err := RenameDefinitions(map[string]string{"Time": "io.k8s.Time", "Test": "io.k8s.Test"})(sp)
+	ast := assert.New(t)
+	if !ast.NoError(err) {
+		return
+	}
+	ast.Contains(sp.Definitions, "Time", "unexpected mutation of input")
+	ast.Equal([]string{"Unused", "UpdateTime", "io.k8s.Test", "io.k8s.Time"}, keys(ret.Definitions))
+	ast.Equal("#/definitions/io.k8s.Test", ret.Paths.Paths["/test"].Post.Parameters[1].Schema.Ref.String())
+	created := ret.Definitions["io.k8s.Test"].Properties["created"]
+	ast.Equal("#/definitions/io.k8s.Time", created.Ref.String())
+
+	_, err = RenameDefinitions(map[string]string{"Time": "Unused"})(sp)
+	ast.EqualError(err, `cannot rename definition "Time" to "Unused": name already in use`)
+	_, err = RenameDefinitions(map[string]string{"Time": "Unused", "Unused": "Time"})(sp)
+	ast.NoError(err)
+	_, err = RenameDefinitions(map[string]string{"Time": "New", "Test": "New"})(sp)
+	ast.EqualError(err, `cannot rename both "Test" and "Time" to "New"`)
+}
+
+func keys(definitions spec.Definitions) []string {
+	ret := make([]string, 0, len(definitions))
+	for k := range definitions {
+		ret = append(ret, k)
+	}
+	return sortedUnique(ret)
+}
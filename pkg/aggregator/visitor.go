@@ -0,0 +1,311 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aggregator
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strconv"
+
+	"github.com/go-openapi/spec"
+
+	"k8s.io/kube-openapi/pkg/common"
+)
+
+// SpecVisitor holds the callbacks called by WalkSpec and RewriteSpec. Every callback gets the JSON pointer
+// (RFC 6901) of the visited element in the spec, e.g. "/paths/~1api~1v1/get/responses/200/schema". Nil
+// callbacks are skipped. Elements are visited before their children, in a deterministic order.
+type SpecVisitor struct {
+	// PathItem is called on every path item under /paths.
+	PathItem func(pointer string, pathItem *spec.PathItem)
+	// Operation is called on every operation of a path item.
+	Operation func(pointer string, op *spec.Operation)
+	// Parameter is called on every parameter of path items and operations, and on the shared parameters under /parameters.
+	Parameter func(pointer string, param *spec.Parameter)
+	// Response is called on every response of operations, and on the shared responses under /responses.
+	Response func(pointer string, resp *spec.Response)
+	// Schema is called on every schema, including definitions and all sub-schemas.
+	Schema func(pointer string, schema *spec.Schema)
+	// Ref is called on every reference of schemas, parameters, responses, and the (nested) items of
+	// parameters and response headers. Empty references are skipped.
+	Ref func(pointer string, ref *spec.Ref)
+	// Extensible is called on the vendor extensions of every element that can have them.
+	Extensible func(pointer string, e *spec.VendorExtensible)
+}
+
+// WalkSpec calls the visitor on all elements of the spec. Unlike the reference walkers used for merging,
+// it does not follow references, but visits every definition, shared parameter and shared response once.
+// The callbacks must not mutate their arguments; use RewriteSpec to modify a spec.
+func WalkSpec(sp *spec.Swagger, v *SpecVisitor) {
+	if sp != nil {
+		(&specWalker{SpecVisitor: v}).walkSpec(sp)
+	}
+}
+
+// RewriteSpec calls the visitor on all elements of a deep copy of the spec, and returns the copy. The
+// callbacks may mutate their arguments in place, changes are visible to the callbacks of children, which
+// are visited afterwards. The input is not mutated.
+func RewriteSpec(sp *spec.Swagger, v *SpecVisitor) (*spec.Swagger, error) {
+	ret, err := deepCopySpec(sp)
+	if err != nil {
+		return nil, err
+	}
+	(&specWalker{SpecVisitor: v, rewrite: true}).walkSpec(ret)
+	return ret, nil
+}
+
+// deepCopySpec returns a copy of the spec that shares no data structures with the input.
+func deepCopySpec(sp *spec.Swagger) (*spec.Swagger, error) {
+	bytes, err := json.Marshal(sp)
+	if err != nil {
+		return nil, err
+	}
+	ret := &spec.Swagger{}
+	if err := json.Unmarshal(bytes, ret); err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
+
+func pointerTo(pointer string, tokens ...string) string {
+	for _, t := range tokens {
+		pointer += "/" + common.EscapeJsonPointer(t)
+	}
+	return pointer
+}
+
+// sortedMapKeys returns the sorted keys of a map with string keys.
+func sortedMapKeys(m interface{}) []string {
+	values := reflect.ValueOf(m).MapKeys()
+	keys := make([]string, len(values))
+	for i, k := range values {
+		keys[i] = k.String()
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// specWalker walks a spec, calling the visitor. It only writes the visited elements back into the
+// maps of the spec if rewrite is set.
+type specWalker struct {
+	*SpecVisitor
+	rewrite bool
+}
+
+func (v *specWalker) walkSpec(sp *spec.Swagger) {
+	v.walkExtensible("", &sp.VendorExtensible)
+	if sp.Info != nil {
+		v.walkExtensible("/info", &sp.Info.VendorExtensible)
+	}
+	if sp.Paths != nil {
+		v.walkExtensible("/paths", &sp.Paths.VendorExtensible)
+		for _, k := range sortedMapKeys(sp.Paths.Paths) {
+			pathItem := sp.Paths.Paths[k]
+			v.walkPathItem(pointerTo("/paths", k), &pathItem)
+			if v.rewrite {
+				sp.Paths.Paths[k] = pathItem
+			}
+		}
+	}
+	for _, k := range sortedMapKeys(sp.Definitions) {
+		s := sp.Definitions[k]
+		v.walkSchema(pointerTo("/definitions", k), &s)
+		if v.rewrite {
+			sp.Definitions[k] = s
+		}
+	}
+	for _, k := range sortedMapKeys(sp.Parameters) {
+		p := sp.Parameters[k]
+		v.walkParameter(pointerTo("/parameters", k), &p)
+		if v.rewrite {
+			sp.Parameters[k] = p
+		}
+	}
+	for _, k := range sortedMapKeys(sp.Responses) {
+		r := sp.Responses[k]
+		v.walkResponse(pointerTo("/responses", k), &r)
+		if v.rewrite {
+			sp.Responses[k] = r
+		}
+	}
+	for _, k := range sortedMapKeys(sp.SecurityDefinitions) {
+		if s := sp.SecurityDefinitions[k]; s != nil {
+			v.walkExtensible(pointerTo("/securityDefinitions", k), &s.VendorExtensible)
+		}
+	}
+	for i := range sp.Tags {
+		v.walkExtensible(pointerTo("/tags", strconv.Itoa(i)), &sp.Tags[i].VendorExtensible)
+	}
+}
+
+func (v *specWalker) walkExtensible(pointer string, e *spec.VendorExtensible) {
+	if v.Extensible != nil {
+		v.Extensible(pointer, e)
+	}
+}
+
+func (v *specWalker) walkRef(pointer string, ref *spec.Ref) {
+	if v.Ref != nil && ref.String() != "" {
+		v.Ref(pointerTo(pointer, "$ref"), ref)
+	}
+}
+
+func (v *specWalker) walkPathItem(pointer string, pathItem *spec.PathItem) {
+	if v.PathItem != nil {
+		v.PathItem(pointer, pathItem)
+	}
+	v.walkExtensible(pointer, &pathItem.VendorExtensible)
+	v.walkParameters(pointer, pathItem.Parameters)
+	for _, op := range []struct {
+		method string
+		op     *spec.Operation
+	}{
+		{"get", pathItem.Get},
+		{"put", pathItem.Put},
+		{"post", pathItem.Post},
+		{"delete", pathItem.Delete},
+		{"options", pathItem.Options},
+		{"head", pathItem.Head},
+		{"patch", pathItem.Patch},
+	} {
+		if op.op != nil {
+			v.walkOperation(pointerTo(pointer, op.method), op.op)
+		}
+	}
+}
+
+func (v *specWalker) walkOperation(pointer string, op *spec.Operation) {
+	if v.Operation != nil {
+		v.Operation(pointer, op)
+	}
+	v.walkExtensible(pointer, &op.VendorExtensible)
+	v.walkParameters(pointer, op.Parameters)
+	if op.Responses == nil {
+		return
+	}
+	pointer = pointerTo(pointer, "responses")
+	v.walkExtensible(pointer, &op.Responses.VendorExtensible)
+	if op.Responses.Default != nil {
+		v.walkResponse(pointerTo(pointer, "default"), op.Responses.Default)
+	}
+	codes := make([]int, 0, len(op.Responses.StatusCodeResponses))
+	for code := range op.Responses.StatusCodeResponses {
+		codes = append(codes, code)
+	}
+	sort.Ints(codes)
+	for _, code := range codes {
+		r := op.Responses.StatusCodeResponses[code]
+		v.walkResponse(pointerTo(pointer, strconv.Itoa(code)), &r)
+		if v.rewrite {
+			op.Responses.StatusCodeResponses[code] = r
+		}
+	}
+}
+
+func (v *specWalker) walkParameters(pointer string, params []spec.Parameter) {
+	for i := range params {
+		v.walkParameter(pointerTo(pointer, "parameters", strconv.Itoa(i)), &params[i])
+	}
+}
+
+func (v *specWalker) walkParameter(pointer string, param *spec.Parameter) {
+	if v.Parameter != nil {
+		v.Parameter(pointer, param)
+	}
+	v.walkExtensible(pointer, &param.VendorExtensible)
+	v.walkRef(pointer, &param.Ref)
+	v.walkItems(pointerTo(pointer, "items"), param.Items)
+	if param.Schema != nil {
+		v.walkSchema(pointerTo(pointer, "schema"), param.Schema)
+	}
+}
+
+func (v *specWalker) walkResponse(pointer string, resp *spec.Response) {
+	if v.Response != nil {
+		v.Response(pointer, resp)
+	}
+	v.walkRef(pointer, &resp.Ref)
+	if resp.Schema != nil {
+		v.walkSchema(pointerTo(pointer, "schema"), resp.Schema)
+	}
+	for _, name := range sortedMapKeys(resp.Headers) {
+		v.walkItems(pointerTo(pointer, "headers", name, "items"), resp.Headers[name].Items)
+	}
+}
+
+// walkItems visits the references of the items of a non-body parameter or a header, down to the
+// innermost items of nested arrays.
+func (v *specWalker) walkItems(pointer string, items *spec.Items) {
+	for items != nil {
+		v.walkRef(pointer, &items.Ref)
+		pointer, items = pointerTo(pointer, "items"), items.Items
+	}
+}
+
+func (v *specWalker) walkSchema(pointer string, schema *spec.Schema) {
+	if v.Schema != nil {
+		v.Schema(pointer, schema)
+	}
+	v.walkExtensible(pointer, &schema.VendorExtensible)
+	v.walkRef(pointer, &schema.Ref)
+	for _, m := range []struct {
+		field   string
+		schemas map[string]spec.Schema
+	}{
+		{"definitions", schema.Definitions},
+		{"properties", schema.Properties},
+		{"patternProperties", schema.PatternProperties},
+	} {
+		for _, k := range sortedMapKeys(m.schemas) {
+			s := m.schemas[k]
+			v.walkSchema(pointerTo(pointer, m.field, k), &s)
+			if v.rewrite {
+				m.schemas[k] = s
+			}
+		}
+	}
+	if schema.AdditionalProperties != nil && schema.AdditionalProperties.Schema != nil {
+		v.walkSchema(pointerTo(pointer, "additionalProperties"), schema.AdditionalProperties.Schema)
+	}
+	if schema.Items != nil {
+		if schema.Items.Schema != nil {
+			v.walkSchema(pointerTo(pointer, "items"), schema.Items.Schema)
+		}
+		for i := range schema.Items.Schemas {
+			v.walkSchema(pointerTo(pointer, "items", strconv.Itoa(i)), &schema.Items.Schemas[i])
+		}
+	}
+	if schema.AdditionalItems != nil && schema.AdditionalItems.Schema != nil {
+		v.walkSchema(pointerTo(pointer, "additionalItems"), schema.AdditionalItems.Schema)
+	}
+	for _, l := range []struct {
+		field   string
+		schemas []spec.Schema
+	}{
+		{"allOf", schema.AllOf},
+		{"anyOf", schema.AnyOf},
+		{"oneOf", schema.OneOf},
+	} {
+		for i := range l.schemas {
+			v.walkSchema(pointerTo(pointer, l.field, strconv.Itoa(i)), &l.schemas[i])
+		}
+	}
+	if schema.Not != nil {
+		v.walkSchema(pointerTo(pointer, "not"), schema.Not)
+	}
+}
@@ -0,0 +1,172 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aggregator
+
+import (
+	"testing"
+
+	"github.com/ghodss/yaml"
+	"github.com/go-openapi/spec"
+	"github.com/stretchr/testify/assert"
+)
+
+const visitorTestSpec = `
+swagger: "2.0"
+paths:
+  /api/v1/{name~}:
+    parameters:
+    - $ref: "#/parameters/name"
+    get:
+      operationId: "getTest"
+      parameters:
+      - in: "body"
+        name: "body"
+        schema:
+          type: "array"
+          items:
+            $ref: "#/definitions/Test"
+      - in: "query"
+        name: "matrix"
+        type: "array"
+        items:
+          type: "array"
+          items:
+            $ref: "#/definitions/Other"
+      responses:
+        404:
+          $ref: "#/responses/NotFound"
+        200:
+          description: "OK"
+          schema:
+            $ref: "#/definitions/Test"
+          headers:
+            X-Other:
+              type: "array"
+              items:
+                $ref: "#/definitions/Other"
+definitions:
+  Test:
+    type: "object"
+    properties:
+      other:
+        $ref: "#/definitions/Other"
+  Other:
+    type: "string"
+parameters:
+  name:
+    in: "path"
+    name: "name"
+    required: true
+    type: "string"
+responses:
+  NotFound:
+    description: "Not found"
+    schema:
+      $ref: "#/definitions/Other"
+`
+
+func TestWalkSpec(t *testing.T) {
+	var sp *spec.Swagger
+	if err := yaml.Unmarshal([]byte(visitorTestSpec), &sp); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	orig, _ := cloneSpec(sp)
+
+	refs := map[string]string{}
+	var operations, schemas, parameters, responses []string
+	WalkSpec(sp, &SpecVisitor{
+		Ref:       func(pointer string, ref *spec.Ref) { refs[pointer] = ref.String() },
+		Operation: func(pointer string, _ *spec.Operation) { operations = append(operations, pointer) },
+		Schema:    func(pointer string, _ *spec.Schema) { schemas = append(schemas, pointer) },
+		Parameter: func(pointer string, _ *spec.Parameter) { parameters = append(parameters, pointer) },
+		Response:  func(pointer string, _ *spec.Response) { responses = append(responses, pointer) },
+	})
+
+	ast := assert.New(t)
+	ast.Equal(DebugSpec{orig}, DebugSpec{sp}, "unexpected mutation of input")
+	ast.Equal(map[string]string{
+		"/paths/~1api~1v1~1{name~0}/parameters/0/$ref":                            "#/parameters/name",
+		"/paths/~1api~1v1~1{name~0}/get/parameters/0/schema/items/$ref":           "#/definitions/Test",
+		"/paths/~1api~1v1~1{name~0}/get/parameters/1/items/items/$ref":            "#/definitions/Other",
+		"/paths/~1api~1v1~1{name~0}/get/responses/200/schema/$ref":                "#/definitions/Test",
+		"/paths/~1api~1v1~1{name~0}/get/responses/200/headers/X-Other/items/$ref": "#/definitions/Other",
+		"/paths/~1api~1v1~1{name~0}/get/responses/404/$ref":                       "#/responses/NotFound",
+		"/definitions/Test/properties/other/$ref":                                 "#/definitions/Other",
+		"/responses/NotFound/schema/$ref":                                         "#/definitions/Other",
+	}, refs)
+	ast.Equal([]string{"/paths/~1api~1v1~1{name~0}/get"}, operations)
+	ast.Equal([]string{
+		"/paths/~1api~1v1~1{name~0}/get/parameters/0/schema",
+		"/paths/~1api~1v1~1{name~0}/get/parameters/0/schema/items",
+		"/paths/~1api~1v1~1{name~0}/get/responses/200/schema",
+		"/definitions/Other",
+		"/definitions/Test",
+		"/definitions/Test/properties/other",
+		"/responses/NotFound/schema",
+	}, schemas)
+	ast.Equal([]string{
+		"/paths/~1api~1v1~1{name~0}/parameters/0",
+		"/paths/~1api~1v1~1{name~0}/get/parameters/0",
+		"/paths/~1api~1v1~1{name~0}/get/parameters/1",
+		"/parameters/name",
+	}, parameters)
+	ast.Equal([]string{
+		"/paths/~1api~1v1~1{name~0}/get/responses/200",
+		"/paths/~1api~1v1~1{name~0}/get/responses/404",
+		"/responses/NotFound",
+	}, responses)
+}
+
+func TestRewriteSpec(t *testing.T) {
+	var sp *spec.Swagger
+	if err := yaml.Unmarshal([]byte(visitorTestSpec), &sp); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	orig, _ := cloneSpec(sp)
+
+	ret, err := RewriteSpec(sp, &SpecVisitor{
+		Ref: func(_ string, ref *spec.Ref) {
+			if ref.String() == "#/definitions/Other" {
+				*ref = spec.MustCreateRef("#/definitions/Renamed")
+			}
+		},
+		Operation: func(_ string, op *spec.Operation) {
+			op.ID = "renamed"
+		},
+	})
+	ast := assert.New(t)
+	if !ast.NoError(err) {
+		return
+	}
+	ast.Equal(DebugSpec{orig}, DebugSpec{sp}, "unexpected mutation of input")
+
+	var renamedRefs []string
+	WalkSpec(ret, &SpecVisitor{
+		Ref: func(pointer string, ref *spec.Ref) {
+			if ref.String() == "#/definitions/Renamed" {
+				renamedRefs = append(renamedRefs, pointer)
+			}
+		},
+	})
+	ast.Equal([]string{
+		"/paths/~1api~1v1~1{name~0}/get/parameters/1/items/items/$ref",
+		"/paths/~1api~1v1~1{name~0}/get/responses/200/headers/X-Other/items/$ref",
+		"/definitions/Test/properties/other/$ref",
+		"/responses/NotFound/schema/$ref",
+	}, renamedRefs)
+	ast.Equal("renamed", ret.Paths.Paths["/api/v1/{name~}"].Get.ID)
+}
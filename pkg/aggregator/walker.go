@@ -24,6 +24,8 @@ import (
 
 const (
 	definitionPrefix = "#/definitions/"
+	parameterPrefix  = "#/parameters/"
+	responsePrefix   = "#/responses/"
 )
 
 // Run a readonlyReferenceWalker method on all references of an OpenAPI spec
@@ -35,9 +37,16 @@ type readonlyReferenceWalker struct {
 	root *spec.Swagger
 }
 
-// walkOnAllReferences recursively walks on all references, while following references into definitions.
+// walkOnAllReferences recursively walks on all references, while following references into definitions,
+// shared parameters and shared responses.
 // it calls walkRef on each found reference.
 func walkOnAllReferences(walkRef func(ref *spec.Ref), root *spec.Swagger) {
+	walkOnReferencesFrom(walkRef, root, (*readonlyReferenceWalker).Start)
+}
+
+// walkOnReferencesFrom is like walkOnAllReferences, but only walks the references reachable from the parts of
+// the spec the start function walks.
+func walkOnReferencesFrom(walkRef func(ref *spec.Ref), root *spec.Swagger, start func(walker *readonlyReferenceWalker)) {
 	alreadyVisited := map[string]bool{}
 
 	walker := &readonlyReferenceWalker{
@@ -47,18 +56,28 @@ func walkOnAllReferences(walkRef func(ref *spec.Ref), root *spec.Swagger) {
 		walkRef(ref)
 
 		refStr := ref.String()
-		if refStr == "" || !strings.HasPrefix(refStr, definitionPrefix) {
+		if refStr == "" || alreadyVisited[refStr] {
 			return
 		}
-		defName := refStr[len(definitionPrefix):]
-
-		if _, found := root.Definitions[defName]; found && !alreadyVisited[refStr] {
-			alreadyVisited[refStr] = true
-			def := root.Definitions[defName]
-			walker.walkSchema(&def)
+		switch {
+		case strings.HasPrefix(refStr, definitionPrefix):
+			if def, found := root.Definitions[refStr[len(definitionPrefix):]]; found {
+				alreadyVisited[refStr] = true
+				walker.walkSchema(&def)
+			}
+		case strings.HasPrefix(refStr, parameterPrefix):
+			if param, found := root.Parameters[refStr[len(parameterPrefix):]]; found {
+				alreadyVisited[refStr] = true
+				walker.walkParams([]spec.Parameter{param})
+			}
+		case strings.HasPrefix(refStr, responsePrefix):
+			if resp, found := root.Responses[refStr[len(responsePrefix):]]; found {
+				alreadyVisited[refStr] = true
+				walker.walkResponse(&resp)
+			}
 		}
 	}
-	walker.Start()
+	start(walker)
 }
 
 func (s *readonlyReferenceWalker) walkSchema(schema *spec.Schema) {
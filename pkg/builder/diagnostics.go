@@ -0,0 +1,174 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package builder
+
+import (
+	"sort"
+	"strings"
+
+	restful "github.com/emicklei/go-restful"
+	"github.com/go-openapi/spec"
+
+	"k8s.io/kube-openapi/pkg/common"
+)
+
+const definitionPrefix = "#/definitions/"
+
+// Diagnostics lists problems of the definitions of a common.Config that do not necessarily fail spec building.
+// All names are canonical type names unless noted otherwise, and all lists are sorted.
+type Diagnostics struct {
+	// UnusedDefinitions are definitions that are not reachable from any path.
+	UnusedDefinitions []string
+	// DanglingDependencies are dependencies of definitions that have no definition themselves.
+	DanglingDependencies []DanglingDependency
+	// DependencyMismatches are definitions whose Dependencies disagree with the $refs in their schema.
+	DependencyMismatches []DependencyMismatch
+}
+
+// DanglingDependency is a dependency without definition.
+type DanglingDependency struct {
+	// Definition is the definition listing the dependency.
+	Definition string
+	// Dependency is the missing definition.
+	Dependency string
+}
+
+// DependencyMismatch describes how the Dependencies of a definition disagree with the $refs in its schema.
+type DependencyMismatch struct {
+	// Definition is the definition with the mismatch.
+	Definition string
+	// UndeclaredRefs are the definition names referenced by the schema that none of the dependencies maps to.
+	UndeclaredRefs []string
+	// UnreferencedDependencies are the dependencies that are not referenced by the schema.
+	UnreferencedDependencies []string
+}
+
+// Empty returns true if no problems were found.
+func (d *Diagnostics) Empty() bool {
+	return len(d.UnusedDefinitions) == 0 && len(d.DanglingDependencies) == 0 && len(d.DependencyMismatches) == 0
+}
+
+// BuildDiagnostics checks the definitions of the config against the paths of the given web services.
+// It fails on the same config and web service errors as BuildOpenAPISpec, except for missing definitions,
+// which are reported as dangling dependencies instead.
+func BuildDiagnostics(webServices []*restful.WebService, config *common.Config) (*Diagnostics, error) {
+	o, err := newOpenAPI(config)
+	if err != nil {
+		return nil, err
+	}
+	o.deferDefinitions = true
+	if err := o.buildPaths(webServices); err != nil {
+		return nil, err
+	}
+
+	used := map[string]bool{}
+	for _, definitions := range o.pathDefinitions {
+		for name := range definitions {
+			o.collectDependencies(name, used)
+		}
+	}
+
+	names := make([]string, 0, len(o.definitions))
+	for name := range o.definitions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	d := &Diagnostics{}
+	for _, name := range names {
+		if !used[name] {
+			d.UnusedDefinitions = append(d.UnusedDefinitions, name)
+		}
+		item := o.definitions[name]
+		deps := append([]string(nil), item.Dependencies...)
+		sort.Strings(deps)
+		for _, dep := range deps {
+			if _, ok := o.definitions[dep]; !ok {
+				d.DanglingDependencies = append(d.DanglingDependencies, DanglingDependency{Definition: name, Dependency: dep})
+			}
+		}
+		if mismatch := o.checkDependencies(name, item.Schema, deps); mismatch != nil {
+			d.DependencyMismatches = append(d.DependencyMismatches, *mismatch)
+		}
+	}
+	return d, nil
+}
+
+// checkDependencies compares the sorted dependencies of a definition with the definition $refs in its schema.
+// It returns nil if they agree.
+func (o *openAPI) checkDependencies(name string, schema spec.Schema, deps []string) *DependencyMismatch {
+	refs := map[string]bool{}
+	walkSchemaRefs(&schema, func(ref string) {
+		if strings.HasPrefix(ref, definitionPrefix) {
+			refs[common.UnescapeJsonPointer(ref[len(definitionPrefix):])] = true
+		}
+	})
+
+	declared := map[string]bool{}
+	mismatch := DependencyMismatch{Definition: name}
+	for _, dep := range deps {
+		defName := o.definitionName(dep)
+		declared[defName] = true
+		if !refs[defName] {
+			mismatch.UnreferencedDependencies = append(mismatch.UnreferencedDependencies, dep)
+		}
+	}
+	for ref := range refs {
+		if !declared[ref] {
+			mismatch.UndeclaredRefs = append(mismatch.UndeclaredRefs, ref)
+		}
+	}
+	if len(mismatch.UndeclaredRefs) == 0 && len(mismatch.UnreferencedDependencies) == 0 {
+		return nil
+	}
+	sort.Strings(mismatch.UndeclaredRefs)
+	return &mismatch
+}
+
+// walkSchemaRefs calls walkRef with every non-empty $ref of the schema and its sub-schemas.
+func walkSchemaRefs(schema *spec.Schema, walkRef func(ref string)) {
+	if schema == nil {
+		return
+	}
+	if ref := schema.Ref.String(); ref != "" {
+		walkRef(ref)
+	}
+	for _, m := range []map[string]spec.Schema{schema.Definitions, schema.Properties, schema.PatternProperties} {
+		for k := range m {
+			s := m[k]
+			walkSchemaRefs(&s, walkRef)
+		}
+	}
+	for _, l := range [][]spec.Schema{schema.AllOf, schema.AnyOf, schema.OneOf} {
+		for i := range l {
+			walkSchemaRefs(&l[i], walkRef)
+		}
+	}
+	walkSchemaRefs(schema.Not, walkRef)
+	if schema.Items != nil {
+		walkSchemaRefs(schema.Items.Schema, walkRef)
+		for i := range schema.Items.Schemas {
+			walkSchemaRefs(&schema.Items.Schemas[i], walkRef)
+		}
+	}
+	if schema.AdditionalProperties != nil {
+		walkSchemaRefs(schema.AdditionalProperties.Schema, walkRef)
+	}
+	if schema.AdditionalItems != nil {
+		walkSchemaRefs(schema.AdditionalItems.Schema, walkRef)
+	}
+}
@@ -0,0 +1,80 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package builder
+
+import (
+	"testing"
+
+	"github.com/emicklei/go-restful"
+	"github.com/go-openapi/spec"
+
+	openapi "k8s.io/kube-openapi/pkg/common"
+)
+
+func TestBuildDiagnostics(t *testing.T) {
+	config, container, assert := setUp(t, false)
+	getDefinitions := config.GetDefinitions
+	config.GetDefinitions = func(ref openapi.ReferenceCallback) map[string]openapi.OpenAPIDefinition {
+		defs := getDefinitions(ref)
+		defs["example.com/api.Unused"] = openapi.OpenAPIDefinition{}
+		defs["example.com/api.Broken"] = openapi.OpenAPIDefinition{
+			Schema: spec.Schema{
+				SchemaProps: spec.SchemaProps{
+					Properties: map[string]spec.Schema{
+						"output": {SchemaProps: spec.SchemaProps{Ref: ref("k8s.io/kube-openapi/pkg/builder.TestOutput")}},
+					},
+				},
+			},
+			Dependencies: []string{"k8s.io/kube-openapi/pkg/builder.TestInput", "example.com/api.Missing"},
+		}
+		return defs
+	}
+	d, err := BuildDiagnostics(container.RegisteredWebServices(), config)
+	if !assert.NoError(err) {
+		return
+	}
+	assert.False(d.Empty())
+	assert.Equal([]string{
+		"example.com/api.Broken",
+		"example.com/api.Unused",
+		"k8s.io/kube-openapi/pkg/builder/go_default_test.TestInput",
+		"k8s.io/kube-openapi/pkg/builder/go_default_test.TestOutput",
+	}, d.UnusedDefinitions)
+	assert.Equal([]DanglingDependency{
+		{Definition: "example.com/api.Broken", Dependency: "example.com/api.Missing"},
+	}, d.DanglingDependencies)
+	assert.Equal([]DependencyMismatch{
+		{
+			Definition:               "example.com/api.Broken",
+			UndeclaredRefs:           []string{"builder.TestOutput"},
+			UnreferencedDependencies: []string{"example.com/api.Missing", "k8s.io/kube-openapi/pkg/builder.TestInput"},
+		},
+	}, d.DependencyMismatches)
+}
+
+func TestBuildDiagnosticsWithDefinitionGetters(t *testing.T) {
+	config, _, assert := setUp(t, false)
+	config.GetDefinitions = func(ref openapi.ReferenceCallback) map[string]openapi.OpenAPIDefinition {
+		return nil
+	}
+	d, err := BuildDiagnostics([]*restful.WebService{getWrapperWebService("/wrapper")}, config)
+	if !assert.NoError(err) {
+		return
+	}
+	// All definitions are provided by OpenAPIDefinitionGetters and are consistent.
+	assert.True(d.Empty(), "%#v", d)
+}
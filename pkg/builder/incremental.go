@@ -0,0 +1,202 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package builder
+
+import (
+	"fmt"
+
+	restful "github.com/emicklei/go-restful"
+	"github.com/go-openapi/spec"
+
+	"k8s.io/kube-openapi/pkg/common"
+)
+
+// IncrementalBuilder builds an OpenAPI spec from a changing set of web services. It caches the paths of every
+// web service and reference counts definitions, so adding or removing a web service only builds or drops the
+// affected paths and definitions. The resulting spec is identical to the one of BuildOpenAPISpec over the
+// current set of web services.
+//
+// An IncrementalBuilder is not safe for concurrent use.
+type IncrementalBuilder struct {
+	o openAPI
+
+	// services maps web services to what they contributed to the spec.
+	services map[*restful.WebService]*webServiceContribution
+	// operationIDs maps the operation IDs of all web services to their paths.
+	operationIDs map[string]string
+	// definitionRefs counts the web services using each canonical type name.
+	definitionRefs map[string]int
+	// definitionNameRefs counts the used canonical type names sharing each definition name.
+	definitionNameRefs map[string]int
+}
+
+// webServiceContribution is what a single web service contributed to the spec.
+type webServiceContribution struct {
+	paths        []string
+	operationIDs []string
+	// definitions are the canonical type names of all definitions used by the paths, including transitive
+	// dependencies.
+	definitions []string
+}
+
+// NewIncrementalBuilder creates an IncrementalBuilder without web services given a common.Config to customize the spec.
+func NewIncrementalBuilder(config *common.Config) (*IncrementalBuilder, error) {
+	o, err := newOpenAPI(config)
+	if err != nil {
+		return nil, err
+	}
+	return &IncrementalBuilder{
+		o:                  o,
+		services:           map[*restful.WebService]*webServiceContribution{},
+		operationIDs:       map[string]string{},
+		definitionRefs:     map[string]int{},
+		definitionNameRefs: map[string]int{},
+	}, nil
+}
+
+// AddWebService builds the paths of the given web service and adds them to the spec. It fails if the web service
+// was added before, or if one of its paths or operation IDs is already in the spec. On failure, the spec is unchanged.
+// To update a web service, remove it and add it again.
+func (b *IncrementalBuilder) AddWebService(w *restful.WebService) error {
+	if _, exists := b.services[w]; exists {
+		return fmt.Errorf("web service %v has already been added", w.RootPath())
+	}
+
+	operationIDs := make(map[string]string, len(b.operationIDs))
+	for id, path := range b.operationIDs {
+		operationIDs[id] = path
+	}
+	paths, pathDefinitions, err := b.o.buildWebServicePaths(w, operationIDs)
+	if err != nil {
+		// building might have added definitions and assigned names before failing.
+		var unused []string
+		for name := range b.o.definitionNames {
+			if b.definitionRefs[name] <= 0 {
+				unused = append(unused, name)
+			}
+		}
+		b.forgetDefinitions(unused)
+		return err
+	}
+
+	c := &webServiceContribution{}
+	used := map[string]bool{}
+	for path, pathItem := range paths {
+		b.o.swagger.Paths.Paths[path] = pathItem
+		b.o.pathDefinitions[path] = pathDefinitions[path]
+		c.paths = append(c.paths, path)
+		for name := range pathDefinitions[path] {
+			b.o.collectDependencies(name, used)
+		}
+	}
+	for id, path := range operationIDs {
+		if _, exists := b.operationIDs[id]; !exists {
+			b.operationIDs[id] = path
+			c.operationIDs = append(c.operationIDs, id)
+		}
+	}
+	for name := range used {
+		if b.definitionRefs[name]++; b.definitionRefs[name] == 1 {
+			b.definitionNameRefs[b.o.definitionName(name)]++
+		}
+		c.definitions = append(c.definitions, name)
+	}
+	b.services[w] = c
+	return nil
+}
+
+// RemoveWebService removes the paths of the given web service from the spec, together with all definitions
+// not used by other web services. It is a no-op if the web service was not added.
+func (b *IncrementalBuilder) RemoveWebService(w *restful.WebService) {
+	c, exists := b.services[w]
+	if !exists {
+		return
+	}
+	delete(b.services, w)
+	for _, path := range c.paths {
+		delete(b.o.swagger.Paths.Paths, path)
+		delete(b.o.pathDefinitions, path)
+	}
+	for _, id := range c.operationIDs {
+		delete(b.operationIDs, id)
+	}
+	var unused []string
+	for _, name := range c.definitions {
+		b.definitionRefs[name]--
+		if b.definitionRefs[name] <= 0 {
+			delete(b.definitionRefs, name)
+			b.definitionNameRefs[b.o.definitionName(name)]--
+			unused = append(unused, name)
+		}
+	}
+	b.forgetDefinitions(unused)
+}
+
+// forgetDefinitions drops the given canonical type names, which are not used by any web service anymore.
+// Their definitions are removed from the spec unless an identical definition of another used type shares the
+// name. Without DisambiguateDefinitionNames, their definition names are released too, so that they can be
+// given to other types later on. Disambiguated names are assigned once for all definitions and are kept.
+func (b *IncrementalBuilder) forgetDefinitions(names []string) {
+	for _, name := range names {
+		defName, assigned := b.o.definitionNames[name]
+		if !assigned {
+			continue
+		}
+		if !b.o.config.DisambiguateDefinitionNames {
+			delete(b.o.definitionNames, name)
+		}
+		if b.definitionNameRefs[defName] > 0 {
+			// built definitions can only be shared by identical ones, but the name must stay with a used type.
+			if b.o.definitionSources[defName] == name {
+				b.o.definitionSources[defName] = b.usedDefinitionWithName(defName)
+			}
+			continue
+		}
+		delete(b.definitionNameRefs, defName)
+		delete(b.o.swagger.Definitions, defName)
+		if !b.o.config.DisambiguateDefinitionNames && b.o.definitionSources[defName] == name {
+			delete(b.o.definitionSources, defName)
+		}
+	}
+}
+
+// usedDefinitionWithName returns a used canonical type name with the given definition name.
+func (b *IncrementalBuilder) usedDefinitionWithName(defName string) string {
+	for name := range b.definitionRefs {
+		if b.o.definitionName(name) == defName {
+			return name
+		}
+	}
+	return ""
+}
+
+// Spec returns the spec of the current set of web services. The config's security and PostProcessSpec
+// are applied to a copy, so the builder can be used further afterwards. The returned spec shares
+// paths and definitions with the builder and must not be mutated below its top-level maps.
+func (b *IncrementalBuilder) Spec() (*spec.Swagger, error) {
+	swagger := &spec.Swagger{}
+	*swagger = *b.o.swagger
+	swagger.Paths = &spec.Paths{Paths: make(map[string]spec.PathItem, len(b.o.swagger.Paths.Paths))}
+	for path, pathItem := range b.o.swagger.Paths.Paths {
+		swagger.Paths.Paths[path] = pathItem
+	}
+	swagger.Definitions = make(spec.Definitions, len(b.o.swagger.Definitions))
+	for defName, def := range b.o.swagger.Definitions {
+		swagger.Definitions[defName] = def
+	}
+	return finalizeSwagger(swagger, b.o.config.SecurityDefinitions, b.o.config.DefaultSecurity, b.o.config.PostProcessSpec)
+}
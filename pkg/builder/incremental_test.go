@@ -0,0 +1,183 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package builder
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/emicklei/go-restful"
+	"github.com/go-openapi/spec"
+	"github.com/stretchr/testify/assert"
+
+	"k8s.io/kube-openapi/pkg/common"
+)
+
+func getWrapperWebService(path string) *restful.WebService {
+	ws := new(restful.WebService)
+	ws.Path(path)
+	ws.Route(ws.GET("/").
+		Operation("get"+path).
+		Produces(restful.MIME_JSON).
+		Returns(200, "OK", &TestWrapper{}).
+		To(noOp))
+	ws.Route(ws.POST("/").
+		Operation("post"+path).
+		Produces(restful.MIME_JSON).
+		Reads(TestInput{}).
+		Returns(200, "OK", TestOutput{}).
+		To(noOp))
+	return ws
+}
+
+func assertIncrementalMatchesFullBuild(t *testing.T, b *IncrementalBuilder, webServices ...*restful.WebService) {
+	config, _ := getConfig(false)
+	assertIncrementalMatchesBuild(t, b, config, webServices...)
+}
+
+func assertIncrementalMatchesBuild(t *testing.T, b *IncrementalBuilder, config *common.Config, webServices ...*restful.WebService) {
+	assert := assert.New(t)
+	expected, err := BuildOpenAPISpec(webServices, config)
+	if !assert.NoError(err) {
+		return
+	}
+	actual, err := b.Spec()
+	if !assert.NoError(err) {
+		return
+	}
+	expectedJSON, err := json.Marshal(expected)
+	if !assert.NoError(err) {
+		return
+	}
+	actualJSON, err := json.Marshal(actual)
+	if !assert.NoError(err) {
+		return
+	}
+	assert.Equal(string(expectedJSON), string(actualJSON))
+}
+
+func TestIncrementalBuilder(t *testing.T) {
+	config, container, assert := setUp(t, true)
+	test := container.RegisteredWebServices()[0]
+	wrapper := getWrapperWebService("/wrapper")
+	other := getWrapperWebService("/other")
+
+	b, err := NewIncrementalBuilder(config)
+	if !assert.NoError(err) {
+		return
+	}
+	assertIncrementalMatchesFullBuild(t, b)
+
+	for _, ws := range []*restful.WebService{test, wrapper, other} {
+		if !assert.NoError(b.AddWebService(ws)) {
+			return
+		}
+	}
+	assertIncrementalMatchesFullBuild(t, b, test, wrapper, other)
+
+	// TestInput and TestOutput are still used by test and other.
+	b.RemoveWebService(wrapper)
+	assertIncrementalMatchesFullBuild(t, b, test, other)
+
+	b.RemoveWebService(other)
+	assertIncrementalMatchesFullBuild(t, b, test)
+
+	assert.NoError(b.AddWebService(wrapper))
+	assertIncrementalMatchesFullBuild(t, b, test, wrapper)
+
+	b.RemoveWebService(test)
+	b.RemoveWebService(test)
+	assertIncrementalMatchesFullBuild(t, b, wrapper)
+}
+
+func TestIncrementalBuilderFailedAdd(t *testing.T) {
+	config, container, assert := setUp(t, false)
+	test := container.RegisteredWebServices()[0]
+
+	b, err := NewIncrementalBuilder(config)
+	if !assert.NoError(err) {
+		return
+	}
+	assert.NoError(b.AddWebService(test))
+	assert.Error(b.AddWebService(test))
+
+	// same path, with new definitions built before the conflict is found
+	conflicting := new(restful.WebService)
+	conflicting.Path("/foo")
+	conflicting.Route(conflicting.GET("/wrapper").Operation("getWrapper").Returns(200, "OK", &TestWrapper{}).To(noOp))
+	conflicting.Route(conflicting.GET("/test/{path}").
+		Operation("conflicting").
+		Param(conflicting.PathParameter("path", "path to the resource").DataType("string")).
+		Returns(200, "OK", &TestWrapper{}).
+		To(noOp))
+	assert.Error(b.AddWebService(conflicting))
+	assertIncrementalMatchesFullBuild(t, b, test)
+
+	// same operation ID
+	ws := getWrapperWebService("/wrapper")
+	ws.Route(ws.GET("/other").Operation("getfooTestInput").Returns(200, "OK", &TestWrapper{}).To(noOp))
+	assert.Error(b.AddWebService(ws))
+	assertIncrementalMatchesFullBuild(t, b, test)
+}
+
+func TestIncrementalBuilderReleasesDefinitionNames(t *testing.T) {
+	config, _, assert := setUp(t, false)
+	getDefinitions := config.GetDefinitions
+	config.GetDefinitions = func(ref common.ReferenceCallback) map[string]common.OpenAPIDefinition {
+		defs := getDefinitions(ref)
+		output := defs["k8s.io/kube-openapi/pkg/builder.TestOutput"]
+		output.Schema.Properties = map[string]spec.Schema{
+			"item": {SchemaProps: spec.SchemaProps{Ref: ref("example.com/other/builder.TestItem")}},
+		}
+		output.Dependencies = []string{"example.com/other/builder.TestItem"}
+		defs["k8s.io/kube-openapi/pkg/builder.TestOutput"] = output
+		defs["example.com/other/builder.TestItem"] = common.OpenAPIDefinition{
+			Schema: spec.Schema{SchemaProps: spec.SchemaProps{Description: "Other test item"}},
+		}
+		return defs
+	}
+	// output and wrapper use different TestItem types with the same definition name.
+	output := new(restful.WebService)
+	output.Path("/output")
+	output.Route(output.GET("/").Operation("getOutput").Returns(200, "OK", TestOutput{}).To(noOp))
+	wrapper := new(restful.WebService)
+	wrapper.Path("/wrapper")
+	wrapper.Route(wrapper.GET("/").Operation("getWrapper").Returns(200, "OK", &TestWrapper{}).To(noOp))
+	both := new(restful.WebService)
+	both.Path("/both")
+	both.Route(both.GET("/output").Operation("getBothOutput").Returns(200, "OK", TestOutput{}).To(noOp))
+	both.Route(both.GET("/wrapper").Operation("getBothWrapper").Returns(200, "OK", &TestWrapper{}).To(noOp))
+
+	b, err := NewIncrementalBuilder(config)
+	if !assert.NoError(err) {
+		return
+	}
+	assert.NoError(b.AddWebService(output))
+	assertIncrementalMatchesBuild(t, b, config, output)
+	b.RemoveWebService(output)
+	assert.NoError(b.AddWebService(wrapper))
+	assertIncrementalMatchesBuild(t, b, config, wrapper)
+	b.RemoveWebService(wrapper)
+	assert.NoError(b.AddWebService(output))
+	assertIncrementalMatchesBuild(t, b, config, output)
+	b.RemoveWebService(output)
+
+	// a failed add must not keep the names it assigned.
+	assert.Error(b.AddWebService(both))
+	assert.NoError(b.AddWebService(wrapper))
+	assertIncrementalMatchesBuild(t, b, config, wrapper)
+}
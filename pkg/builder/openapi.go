@@ -20,6 +20,8 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"reflect"
+	"sort"
 	"strings"
 
 	restful "github.com/emicklei/go-restful"
@@ -40,12 +42,30 @@ type openAPI struct {
 	swagger      *spec.Swagger
 	protocolList []string
 	definitions  map[string]common.OpenAPIDefinition
+	typeFormats  common.TypeFormatRegistry
+
+	// definitionNames maps canonical type names to their unique definition names.
+	definitionNames map[string]string
+	// definitionSources maps definition names to the canonical type name they were first given to.
+	definitionSources map[string]string
+	// visitedTypes are the types registerDefinitionGetters walked already.
+	visitedTypes map[reflect.Type]bool
+
+	// pathDefinitions maps paths to the canonical type names directly referenced by their operations.
+	pathDefinitions map[string]map[string]bool
+	// usedDefinitions collects the canonical type names referenced while building a path.
+	usedDefinitions map[string]bool
+	// deferDefinitions makes path building only collect the used definitions instead of building them.
+	deferDefinitions bool
 }
 
 // BuildOpenAPISpec builds OpenAPI spec given a list of webservices (containing routes) and common.Config to customize it.
 func BuildOpenAPISpec(webServices []*restful.WebService, config *common.Config) (*spec.Swagger, error) {
-	o := newOpenAPI(config)
-	err := o.buildPaths(webServices)
+	o, err := newOpenAPI(config)
+	if err != nil {
+		return nil, err
+	}
+	err = o.buildPaths(webServices)
 	if err != nil {
 		return nil, err
 	}
@@ -54,10 +74,13 @@ func BuildOpenAPISpec(webServices []*restful.WebService, config *common.Config)
 
 // BuildOpenAPIDefinitionsForResource builds a partial OpenAPI spec given a sample object and common.Config to customize it.
 func BuildOpenAPIDefinitionsForResource(model interface{}, config *common.Config) (*spec.Definitions, error) {
-	o := newOpenAPI(config)
+	o, err := newOpenAPI(config)
+	if err != nil {
+		return nil, err
+	}
 	// We can discard the return value of toSchema because all we care about is the side effect of calling it.
 	// All the models created for this resource get added to o.swagger.Definitions
-	_, err := o.toSchema(util.GetCanonicalTypeName(model))
+	_, err = o.toSchemaForSample(model)
 	if err != nil {
 		return nil, err
 	}
@@ -71,7 +94,10 @@ func BuildOpenAPIDefinitionsForResource(model interface{}, config *common.Config
 // BuildOpenAPIDefinitionsForResources returns the OpenAPI spec which includes the definitions for the
 // passed type names.
 func BuildOpenAPIDefinitionsForResources(config *common.Config, names ...string) (*spec.Swagger, error) {
-	o := newOpenAPI(config)
+	o, err := newOpenAPI(config)
+	if err != nil {
+		return nil, err
+	}
 	// We can discard the return value of toSchema because all we care about is the side effect of calling it.
 	// All the models created for this resource get added to o.swagger.Definitions
 	for _, name := range names {
@@ -83,8 +109,11 @@ func BuildOpenAPIDefinitionsForResources(config *common.Config, names ...string)
 	return o.finalizeSwagger()
 }
 
-// newOpenAPI sets up the openAPI object so we can build the spec.
-func newOpenAPI(config *common.Config) openAPI {
+// newOpenAPI validates the config and sets up the openAPI object so we can build the spec.
+func newOpenAPI(config *common.Config) (openAPI, error) {
+	if err := config.Validate(); err != nil {
+		return openAPI{}, err
+	}
 	o := openAPI{
 		config: config,
 		swagger: &spec.Swagger{
@@ -96,6 +125,11 @@ func newOpenAPI(config *common.Config) openAPI {
 				Info:        config.Info,
 			},
 		},
+		typeFormats:       common.DefaultTypeFormats().Extend(config.TypeFormats),
+		definitionNames:   map[string]string{},
+		definitionSources: map[string]string{},
+		visitedTypes:      map[reflect.Type]bool{},
+		pathDefinitions:   map[string]map[string]bool{},
 	}
 	if o.config.GetOperationIDAndTags == nil {
 		o.config.GetOperationIDAndTags = func(r *restful.Route) (string, []string, error) {
@@ -107,151 +141,269 @@ func newOpenAPI(config *common.Config) openAPI {
 			return name[strings.LastIndex(name, "/")+1:], nil
 		}
 	}
+	// Without DisambiguateDefinitionNames, names are only checked for collisions when their definitions are built.
 	o.definitions = o.config.GetDefinitions(func(name string) spec.Ref {
-		defName, _ := o.config.GetDefinitionName(name)
-		return spec.MustCreateRef("#/definitions/" + common.EscapeJsonPointer(defName))
+		return spec.MustCreateRef(definitionPrefix + common.EscapeJsonPointer(o.referenceName(name)))
 	})
+	if o.config.DisambiguateDefinitionNames {
+		o.assignRemainingDefinitionNames()
+	}
+	if o.definitions == nil {
+		o.definitions = map[string]common.OpenAPIDefinition{}
+	}
 	if o.config.CommonResponses == nil {
 		o.config.CommonResponses = map[int]spec.Response{}
 	}
-	return o
+	return o, nil
 }
 
 // finalizeSwagger is called after the spec is built and returns the final spec.
 // NOTE: finalizeSwagger also make changes to the final spec, as specified in the config.
 func (o *openAPI) finalizeSwagger() (*spec.Swagger, error) {
-	if o.config.SecurityDefinitions != nil {
-		o.swagger.SecurityDefinitions = *o.config.SecurityDefinitions
-		o.swagger.Security = o.config.DefaultSecurity
+	var err error
+	o.swagger, err = finalizeSwagger(o.swagger, o.config.SecurityDefinitions, o.config.DefaultSecurity, o.config.PostProcessSpec)
+	return o.swagger, err
+}
+
+// finalizeSwagger sets the security of the given spec and runs the post-processing function if any.
+func finalizeSwagger(swagger *spec.Swagger, securityDefinitions *spec.SecurityDefinitions, defaultSecurity []map[string][]string, postProcessSpec func(*spec.Swagger) (*spec.Swagger, error)) (*spec.Swagger, error) {
+	if securityDefinitions != nil {
+		swagger.SecurityDefinitions = *securityDefinitions
+		swagger.Security = defaultSecurity
 	}
-	if o.config.PostProcessSpec != nil {
-		var err error
-		o.swagger, err = o.config.PostProcessSpec(o.swagger)
-		if err != nil {
-			return nil, err
+	if postProcessSpec != nil {
+		return postProcessSpec(swagger)
+	}
+	return swagger, nil
+}
+
+// referenceName returns the definition name to reference the given canonical type name by. With
+// DisambiguateDefinitionNames, referenced names are assigned right away, so that references never change
+// afterwards. Types referenced by other definitions are thus named in the order GetDefinitions references them.
+func (o *openAPI) referenceName(name string) string {
+	if o.config.DisambiguateDefinitionNames {
+		// Disambiguated names cannot collide.
+		o.assignDefinitionName(name)
+	}
+	return o.definitionName(name)
+}
+
+// assignRemainingDefinitionNames assigns names to all definitions not referenced by other definitions, in
+// canonical name order.
+func (o *openAPI) assignRemainingDefinitionNames() {
+	names := make([]string, 0, len(o.definitions))
+	for name := range o.definitions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		o.assignDefinitionName(name)
+	}
+}
+
+// assignDefinitionName records the definition name of the given canonical type name. If
+// GetDefinitionName returns a name that is already taken by a different definition, the
+// collision is an error, or, if config.DisambiguateDefinitionNames is set, a unique name is
+// derived by appending a _v<N> suffix. It returns true if the name was disambiguated.
+func (o *openAPI) assignDefinitionName(name string) (bool, error) {
+	if _, ok := o.definitionNames[name]; ok {
+		return false, nil
+	}
+	base, _ := o.config.GetDefinitionName(name)
+	defName := base
+	for i := 2; o.isDefinitionNameTaken(defName, name); i++ {
+		if !o.config.DisambiguateDefinitionNames {
+			return false, fmt.Errorf("definition name %q is used for both %q and %q. GetDefinitionName must return unique names, or set DisambiguateDefinitionNames", defName, o.definitionSources[defName], name)
 		}
+		defName = fmt.Sprintf("%s_v%d", base, i)
 	}
+	o.definitionNames[name] = defName
+	if _, taken := o.definitionSources[defName]; !taken {
+		o.definitionSources[defName] = name
+	}
+	return defName != base, nil
+}
+
+// isDefinitionNameTaken returns true if defName was given to a type other than name, unless both definitions
+// are known and identical.
+func (o *openAPI) isDefinitionNameTaken(defName, name string) bool {
+	other, taken := o.definitionSources[defName]
+	if !taken || other == name {
+		return false
+	}
+	otherItem, otherKnown := o.definitions[other]
+	item, known := o.definitions[name]
+	return !otherKnown || !known || !reflect.DeepEqual(otherItem, item)
+}
 
-	return o.swagger, nil
+// definitionName returns the definition name of the given canonical type name.
+func (o *openAPI) definitionName(name string) string {
+	if defName, ok := o.definitionNames[name]; ok {
+		return defName
+	}
+	defName, _ := o.config.GetDefinitionName(name)
+	return defName
 }
 
 func (o *openAPI) buildDefinitionRecursively(name string) error {
-	uniqueName, extensions := o.config.GetDefinitionName(name)
-	if _, ok := o.swagger.Definitions[uniqueName]; ok {
+	uniqueName := o.definitionName(name)
+	if _, ok := o.swagger.Definitions[uniqueName]; ok && o.definitionSources[uniqueName] == name {
 		return nil
 	}
 	if item, ok := o.definitions[name]; ok {
-		schema := spec.Schema{
-			VendorExtensible:   item.Schema.VendorExtensible,
-			SchemaProps:        item.Schema.SchemaProps,
-			SwaggerSchemaProps: item.Schema.SwaggerSchemaProps,
-		}
-		if extensions != nil {
-			if schema.Extensions == nil {
-				schema.Extensions = spec.Extensions{}
-			}
-			for k, v := range extensions {
-				schema.Extensions[k] = v
-			}
+		if _, err := o.assignDefinitionName(name); err != nil {
+			return err
+		}
+		if _, ok := o.swagger.Definitions[uniqueName]; ok {
+			// Another type with an identical definition was given the same name.
+			return nil
 		}
-		o.swagger.Definitions[uniqueName] = schema
+		o.swagger.Definitions[uniqueName] = o.buildDefinition(name, item)
 		for _, v := range item.Dependencies {
 			if err := o.buildDefinitionRecursively(v); err != nil {
 				return err
 			}
 		}
 	} else {
-		return fmt.Errorf("cannot find model definition for %v. If you added a new type, you may need to add +k8s:openapi-gen=true to the package or type and run code-gen again", name)
+		return missingDefinitionError(name)
 	}
 	return nil
 }
 
+// buildDefinition returns the schema of the given definition, with the extensions returned by GetDefinitionName.
+func (o *openAPI) buildDefinition(name string, item common.OpenAPIDefinition) spec.Schema {
+	_, extensions := o.config.GetDefinitionName(name)
+	schema := spec.Schema{
+		VendorExtensible:   item.Schema.VendorExtensible,
+		SchemaProps:        item.Schema.SchemaProps,
+		SwaggerSchemaProps: item.Schema.SwaggerSchemaProps,
+	}
+	if extensions != nil {
+		if schema.Extensions == nil {
+			schema.Extensions = spec.Extensions{}
+		}
+		for k, v := range extensions {
+			schema.Extensions[k] = v
+		}
+	}
+	return schema
+}
+
+func missingDefinitionError(name string) error {
+	return fmt.Errorf("cannot find model definition for %v. If you added a new type, you may need to add +k8s:openapi-gen=true to the package or type and run code-gen again", name)
+}
+
 // buildDefinitionForType build a definition for a given type and return a referable name to its definition.
 // This is the main function that keep track of definitions used in this spec and is depend on code generated
 // by k8s.io/kubernetes/cmd/libs/go2idl/openapi-gen.
 func (o *openAPI) buildDefinitionForType(name string) (string, error) {
-	if err := o.buildDefinitionRecursively(name); err != nil {
-		return "", err
+	if !o.deferDefinitions {
+		if err := o.buildDefinitionRecursively(name); err != nil {
+			return "", err
+		}
 	}
-	defName, _ := o.config.GetDefinitionName(name)
-	return "#/definitions/" + common.EscapeJsonPointer(defName), nil
+	if o.usedDefinitions != nil {
+		o.usedDefinitions[name] = true
+	}
+	return "#/definitions/" + common.EscapeJsonPointer(o.definitionName(name)), nil
 }
 
 // buildPaths builds OpenAPI paths using go-restful's web services.
 func (o *openAPI) buildPaths(webServices []*restful.WebService) error {
-	pathsToIgnore := util.NewTrie(o.config.IgnorePrefixes)
 	duplicateOpId := make(map[string]string)
 	for _, w := range webServices {
-		rootPath := w.RootPath()
-		if pathsToIgnore.HasPrefix(rootPath) {
+		paths, pathDefinitions, err := o.buildWebServicePaths(w, duplicateOpId)
+		if err != nil {
+			return err
+		}
+		for path, pathItem := range paths {
+			o.swagger.Paths.Paths[path] = pathItem
+			o.pathDefinitions[path] = pathDefinitions[path]
+		}
+	}
+	return nil
+}
+
+// buildWebServicePaths builds the OpenAPI paths of a single go-restful web service, and returns them together
+// with the canonical type names directly referenced by each path. It fails on paths that already exist in the spec
+// and on operation IDs found in duplicateOpId, which maps operation IDs to paths and is extended by the new operations.
+// Definitions are added to the spec as a side effect.
+func (o *openAPI) buildWebServicePaths(w *restful.WebService, duplicateOpId map[string]string) (map[string]spec.PathItem, map[string]map[string]bool, error) {
+	paths := map[string]spec.PathItem{}
+	pathDefinitions := map[string]map[string]bool{}
+	pathsToIgnore := util.NewTrie(o.config.IgnorePrefixes)
+	rootPath := w.RootPath()
+	if pathsToIgnore.HasPrefix(rootPath) {
+		return paths, pathDefinitions, nil
+	}
+	commonParams, err := o.buildParameters(w.PathParameters())
+	if err != nil {
+		return nil, nil, err
+	}
+	defer func() { o.usedDefinitions = nil }()
+	for path, routes := range groupRoutesByPath(w.Routes()) {
+		// go-swagger has special variable definition {$NAME:*} that can only be
+		// used at the end of the path and it is not recognized by OpenAPI.
+		if strings.HasSuffix(path, ":*}") {
+			path = path[:len(path)-3] + "}"
+		}
+		if pathsToIgnore.HasPrefix(path) {
 			continue
 		}
-		commonParams, err := o.buildParameters(w.PathParameters())
+		o.usedDefinitions = map[string]bool{}
+		// Aggregating common parameters make API spec (and generated clients) simpler
+		inPathCommonParamsMap, err := o.findCommonParameters(routes)
 		if err != nil {
-			return err
+			return nil, nil, err
 		}
-		for path, routes := range groupRoutesByPath(w.Routes()) {
-			// go-swagger has special variable definition {$NAME:*} that can only be
-			// used at the end of the path and it is not recognized by OpenAPI.
-			if strings.HasSuffix(path, ":*}") {
-				path = path[:len(path)-3] + "}"
-			}
-			if pathsToIgnore.HasPrefix(path) {
-				continue
-			}
-			// Aggregating common parameters make API spec (and generated clients) simpler
-			inPathCommonParamsMap, err := o.findCommonParameters(routes)
+		_, exists := o.swagger.Paths.Paths[path]
+		if _, existsInWebService := paths[path]; exists || existsInWebService {
+			return nil, nil, fmt.Errorf("duplicate webservice route has been found for path: %v", path)
+		}
+		pathItem := spec.PathItem{
+			PathItemProps: spec.PathItemProps{
+				Parameters: make([]spec.Parameter, 0),
+			},
+		}
+		// add web services's parameters as well as any parameters appears in all ops, as common parameters
+		pathItem.Parameters = append(pathItem.Parameters, commonParams...)
+		for _, p := range inPathCommonParamsMap {
+			pathItem.Parameters = append(pathItem.Parameters, p)
+		}
+		sortParameters(pathItem.Parameters)
+		for _, route := range routes {
+			op, err := o.buildOperations(route, inPathCommonParamsMap)
+			sortParameters(op.Parameters)
 			if err != nil {
-				return err
+				return nil, nil, err
 			}
-			pathItem, exists := o.swagger.Paths.Paths[path]
+			dpath, exists := duplicateOpId[op.ID]
 			if exists {
-				return fmt.Errorf("duplicate webservice route has been found for path: %v", path)
-			}
-			pathItem = spec.PathItem{
-				PathItemProps: spec.PathItemProps{
-					Parameters: make([]spec.Parameter, 0),
-				},
+				return nil, nil, fmt.Errorf("duplicate Operation ID %v for path %v and %v", op.ID, dpath, path)
+			} else {
+				duplicateOpId[op.ID] = path
 			}
-			// add web services's parameters as well as any parameters appears in all ops, as common parameters
-			pathItem.Parameters = append(pathItem.Parameters, commonParams...)
-			for _, p := range inPathCommonParamsMap {
-				pathItem.Parameters = append(pathItem.Parameters, p)
+			switch strings.ToUpper(route.Method) {
+			case "GET":
+				pathItem.Get = op
+			case "POST":
+				pathItem.Post = op
+			case "HEAD":
+				pathItem.Head = op
+			case "PUT":
+				pathItem.Put = op
+			case "DELETE":
+				pathItem.Delete = op
+			case "OPTIONS":
+				pathItem.Options = op
+			case "PATCH":
+				pathItem.Patch = op
 			}
-			sortParameters(pathItem.Parameters)
-			for _, route := range routes {
-				op, err := o.buildOperations(route, inPathCommonParamsMap)
-				sortParameters(op.Parameters)
-				if err != nil {
-					return err
-				}
-				dpath, exists := duplicateOpId[op.ID]
-				if exists {
-					return fmt.Errorf("duplicate Operation ID %v for path %v and %v", op.ID, dpath, path)
-				} else {
-					duplicateOpId[op.ID] = path
-				}
-				switch strings.ToUpper(route.Method) {
-				case "GET":
-					pathItem.Get = op
-				case "POST":
-					pathItem.Post = op
-				case "HEAD":
-					pathItem.Head = op
-				case "PUT":
-					pathItem.Put = op
-				case "DELETE":
-					pathItem.Delete = op
-				case "OPTIONS":
-					pathItem.Options = op
-				case "PATCH":
-					pathItem.Patch = op
-				}
-			}
-			o.swagger.Paths.Paths[path] = pathItem
 		}
+		paths[path] = pathItem
+		pathDefinitions[path] = o.usedDefinitions
 	}
-	return nil
+	return paths, pathDefinitions, nil
 }
 
 // buildOperations builds operations for each webservice path
@@ -281,8 +433,9 @@ func (o *openAPI) buildOperations(route restful.Route, inPathCommonParamsMap map
 		return ret, err
 	}
 
-	// Build responses
-	for _, resp := range route.ResponseErrors {
+	// Build responses by status code. Definition getters of the response models are registered on the way, so
+	// with DisambiguateDefinitionNames the assigned names would otherwise depend on the map iteration order.
+	for _, resp := range sortedResponseErrors(route) {
 		ret.Responses.StatusCodeResponses[resp.Code], err = o.buildResponse(resp.Model, resp.Message)
 		if err != nil {
 			return ret, err
@@ -320,7 +473,7 @@ func (o *openAPI) buildOperations(route restful.Route, inPathCommonParamsMap map
 }
 
 func (o *openAPI) buildResponse(model interface{}, description string) (spec.Response, error) {
-	schema, err := o.toSchema(util.GetCanonicalTypeName(model))
+	schema, err := o.toSchemaForSample(model)
 	if err != nil {
 		return spec.Response{}, err
 	}
@@ -366,11 +519,12 @@ func (o *openAPI) findCommonParameters(routes []restful.Route) (map[interface{}]
 }
 
 func (o *openAPI) toSchema(name string) (_ *spec.Schema, err error) {
-	if openAPIType, openAPIFormat := common.GetOpenAPITypeFormat(name); openAPIType != "" {
+	if tf, ok := o.typeFormats.Lookup(name); ok {
 		return &spec.Schema{
 			SchemaProps: spec.SchemaProps{
-				Type:   []string{openAPIType},
-				Format: openAPIFormat,
+				Type:    []string{tf.Type},
+				Format:  tf.Format,
+				Pattern: tf.Pattern,
 			},
 		}, nil
 	} else {
@@ -386,6 +540,85 @@ func (o *openAPI) toSchema(name string) (_ *spec.Schema, err error) {
 	}
 }
 
+// toSchemaForSample registers definitions provided by the sample object (and the types it is
+// composed of) through common.OpenAPIDefinitionGetter and returns the schema for the sample's type.
+func (o *openAPI) toSchemaForSample(sample interface{}) (*spec.Schema, error) {
+	if err := o.registerDefinitionGetters(reflect.TypeOf(sample)); err != nil {
+		return nil, err
+	}
+	return o.toSchema(util.GetCanonicalTypeName(sample))
+}
+
+// registerDefinitionGetters walks the given type and all types reachable through its fields, elements
+// and keys. Every named type implementing common.OpenAPIDefinitionGetter that has not been registered
+// by config.GetDefinitions is added to the known definitions. Definitions from GetDefinitions always win.
+// Every type is only walked once per builder, no matter how many routes use it.
+func (o *openAPI) registerDefinitionGetters(t reflect.Type) error {
+	if t == nil || o.visitedTypes[t] {
+		return nil
+	}
+	o.visitedTypes[t] = true
+
+	if t.Name() != "" && t.Kind() != reflect.Interface {
+		sample := reflect.New(t)
+		var getter common.OpenAPIDefinitionGetter
+		if g, ok := sample.Elem().Interface().(common.OpenAPIDefinitionGetter); ok {
+			getter = g
+		} else if g, ok := sample.Interface().(common.OpenAPIDefinitionGetter); ok {
+			getter = g
+		}
+		if getter != nil {
+			name := util.GetCanonicalTypeName(sample.Elem().Interface())
+			if _, ok := o.definitions[name]; !ok {
+				if def := getter.OpenAPIDefinition(); def != nil {
+					o.definitions[name] = *def
+					if o.config.DisambiguateDefinitionNames {
+						if _, err := o.assignDefinitionName(name); err != nil {
+							return err
+						}
+					}
+				}
+			}
+		}
+	}
+
+	switch t.Kind() {
+	case reflect.Ptr, reflect.Slice, reflect.Array:
+		return o.registerDefinitionGetters(t.Elem())
+	case reflect.Map:
+		if err := o.registerDefinitionGetters(t.Key()); err != nil {
+			return err
+		}
+		return o.registerDefinitionGetters(t.Elem())
+	case reflect.Struct:
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			// Unexported fields are never serialized, so they cannot contribute definitions.
+			if f.PkgPath != "" && !f.Anonymous {
+				continue
+			}
+			if err := o.registerDefinitionGetters(f.Type); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// sortedResponseErrors returns the responses of the route ordered by status code.
+func sortedResponseErrors(route restful.Route) []restful.ResponseError {
+	codes := make([]int, 0, len(route.ResponseErrors))
+	for code := range route.ResponseErrors {
+		codes = append(codes, code)
+	}
+	sort.Ints(codes)
+	ret := make([]restful.ResponseError, len(codes))
+	for i, code := range codes {
+		ret[i] = route.ResponseErrors[code]
+	}
+	return ret
+}
+
 func (o *openAPI) buildParameter(restParam restful.ParameterData, bodySample interface{}) (ret spec.Parameter, err error) {
 	ret = spec.Parameter{
 		ParamProps: spec.ParamProps{
@@ -398,7 +631,7 @@ func (o *openAPI) buildParameter(restParam restful.ParameterData, bodySample int
 	case restful.BodyParameterKind:
 		if bodySample != nil {
 			ret.In = "body"
-			ret.Schema, err = o.toSchema(util.GetCanonicalTypeName(bodySample))
+			ret.Schema, err = o.toSchemaForSample(bodySample)
 			return ret, err
 		} else {
 			// There is not enough information in the body parameter to build the definition.
@@ -420,12 +653,13 @@ func (o *openAPI) buildParameter(restParam restful.ParameterData, bodySample int
 	default:
 		return ret, fmt.Errorf("unknown restful operation kind : %v", restParam.Kind)
 	}
-	openAPIType, openAPIFormat := common.GetOpenAPITypeFormat(restParam.DataType)
-	if openAPIType == "" {
+	tf, ok := o.typeFormats.Lookup(restParam.DataType)
+	if !ok {
 		return ret, fmt.Errorf("non-body Restful parameter type should be a simple type, but got : %v", restParam.DataType)
 	}
-	ret.Type = openAPIType
-	ret.Format = openAPIFormat
+	ret.Type = tf.Type
+	ret.Format = tf.Format
+	ret.Pattern = tf.Pattern
 	ret.UniqueItems = !restParam.AllowMultiple
 	return ret, nil
 }
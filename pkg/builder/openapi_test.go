@@ -20,8 +20,10 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"reflect"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/emicklei/go-restful"
 	"github.com/go-openapi/spec"
@@ -29,6 +31,9 @@ import (
 	openapi "k8s.io/kube-openapi/pkg/common"
 )
 
+// isBazelTest is true if Bazel changed the package name of the test types.
+var isBazelTest = strings.HasSuffix(reflect.TypeOf(TestInput{}).PkgPath(), "/go_default_test")
+
 // setUp is a convenience function for setting up for (most) tests.
 func setUp(t *testing.T, fullMethods bool) (*openapi.Config, *restful.Container, *assert.Assertions) {
 	assert := assert.New(t)
@@ -192,7 +197,9 @@ func getConfig(fullMethods bool) (*openapi.Config, *restful.Container) {
 		},
 		GetDefinitionName: func(name string) (string, spec.Extensions) {
 			friendlyName := name[strings.LastIndex(name, "/")+1:]
-			if strings.HasPrefix(friendlyName, "go_default_test") {
+			// Only rename the Bazel package when testing with Bazel, so that tests overriding the definition
+			// of one package do not map two different definitions to the same name.
+			if strings.HasPrefix(friendlyName, "go_default_test") && isBazelTest {
 				friendlyName = "builder" + friendlyName[len("go_default_test"):]
 			}
 			return friendlyName, spec.Extensions{"x-test2": "test2"}
@@ -473,3 +480,230 @@ func TestBuildOpenAPIDefinitionsForResource(t *testing.T) {
 	}
 	assert.Equal(string(expected_json), string(actual_json))
 }
+
+// TestWrapper is not registered in GetDefinitions and only provides its definition
+// through OpenAPIDefinitionGetter.
+type TestWrapper struct {
+	Item TestItem `json:"item"`
+}
+
+// TestItem is only reachable through a field of TestWrapper.
+type TestItem struct {
+	Value string `json:"value"`
+}
+
+func (_ TestWrapper) OpenAPIDefinition() *openapi.OpenAPIDefinition {
+	schema := spec.Schema{}
+	schema.Description = "Test wrapper"
+	schema.Properties = map[string]spec.Schema{
+		"item": *getRefSchema("#/definitions/builder.TestItem"),
+	}
+	return &openapi.OpenAPIDefinition{
+		Schema:       schema,
+		Dependencies: []string{"k8s.io/kube-openapi/pkg/builder.TestItem"},
+	}
+}
+
+func (_ *TestItem) OpenAPIDefinition() *openapi.OpenAPIDefinition {
+	schema := spec.Schema{}
+	schema.Description = "Test item"
+	schema.Type = []string{"string"}
+	return &openapi.OpenAPIDefinition{
+		Schema: schema,
+	}
+}
+
+func TestBuildOpenAPISpecWithDefinitionGetters(t *testing.T) {
+	config, _, assert := setUp(t, false)
+	container := restful.NewContainer()
+	ws := new(restful.WebService)
+	ws.Path("/wrapper")
+	ws.Route(ws.GET("/").
+		Operation("getWrapper").
+		Produces(restful.MIME_JSON).
+		Returns(200, "OK", &TestWrapper{}).
+		To(noOp))
+	container.Add(ws)
+
+	swagger, err := BuildOpenAPISpec(container.RegisteredWebServices(), config)
+	if !assert.NoError(err) {
+		return
+	}
+	if assert.Contains(swagger.Definitions, "builder.TestWrapper") {
+		assert.Equal("Test wrapper", swagger.Definitions["builder.TestWrapper"].Description)
+	}
+	if assert.Contains(swagger.Definitions, "builder.TestItem") {
+		assert.Equal("Test item", swagger.Definitions["builder.TestItem"].Description)
+	}
+	assert.Equal("#/definitions/builder.TestWrapper", swagger.Paths.Paths["/wrapper/"].Get.Responses.StatusCodeResponses[200].Schema.Ref.String())
+}
+
+func TestRegisterDefinitionGettersVisitsTypesOnce(t *testing.T) {
+	config, _, assert := setUp(t, false)
+	o, err := newOpenAPI(config)
+	if !assert.NoError(err) {
+		return
+	}
+	assert.NoError(o.registerDefinitionGetters(reflect.TypeOf(&TestWrapper{})))
+	assert.Contains(o.definitions, "k8s.io/kube-openapi/pkg/builder.TestItem")
+
+	// Walking another route with the same sample does not walk its types again.
+	delete(o.definitions, "k8s.io/kube-openapi/pkg/builder.TestItem")
+	assert.NoError(o.registerDefinitionGetters(reflect.TypeOf(TestWrapper{})))
+	assert.NotContains(o.definitions, "k8s.io/kube-openapi/pkg/builder.TestItem")
+}
+
+func TestBuildOpenAPISpecPrefersGetDefinitions(t *testing.T) {
+	config, _, assert := setUp(t, false)
+	getDefinitions := config.GetDefinitions
+	config.GetDefinitions = func(ref openapi.ReferenceCallback) map[string]openapi.OpenAPIDefinition {
+		defs := getDefinitions(ref)
+		def := *TestOutput{}.OpenAPIDefinition()
+		def.Schema.Description = "registered"
+		defs["k8s.io/kube-openapi/pkg/builder.TestOutput"] = def
+		return defs
+	}
+	definitions, err := BuildOpenAPIDefinitionsForResource(TestOutput{}, config)
+	if !assert.NoError(err) {
+		return
+	}
+	assert.Equal("registered", (*definitions)["builder.TestOutput"].Description)
+}
+
+func TestBuildOpenAPISpecWithTypeFormats(t *testing.T) {
+	config, _, assert := setUp(t, false)
+	config.TypeFormats = openapi.TypeFormatRegistry{
+		"time.Duration": {Type: "string", Format: "duration"},
+		"color":         {Type: "string", Pattern: "^#[0-9a-f]{6}$"},
+	}
+	container := restful.NewContainer()
+	ws := new(restful.WebService)
+	ws.Path("/timeout")
+	ws.Route(ws.GET("/").
+		Operation("getTimeout").
+		Produces(restful.MIME_JSON).
+		Param(ws.QueryParameter("color", "a color").DataType("color")).
+		Returns(200, "OK", time.Duration(0)).
+		To(noOp))
+	container.Add(ws)
+
+	swagger, err := BuildOpenAPISpec(container.RegisteredWebServices(), config)
+	if !assert.NoError(err) {
+		return
+	}
+	op := swagger.Paths.Paths["/timeout/"].Get
+	schema := op.Responses.StatusCodeResponses[200].Schema
+	assert.Equal(spec.StringOrArray{"string"}, schema.Type)
+	assert.Equal("duration", schema.Format)
+	assert.Empty(swagger.Definitions)
+	var param spec.Parameter
+	for _, p := range swagger.Paths.Paths["/timeout/"].Parameters {
+		if p.Name == "color" {
+			param = p
+		}
+	}
+	assert.Equal("string", param.Type)
+	assert.Equal("^#[0-9a-f]{6}$", param.Pattern)
+}
+
+func TestBuildOpenAPISpecValidatesConfig(t *testing.T) {
+	config, container, assert := setUp(t, false)
+	config.DefaultSecurity = []map[string][]string{{"BearerToken": {}}}
+	_, err := BuildOpenAPISpec(container.RegisteredWebServices(), config)
+	if assert.Error(err) {
+		assert.Contains(err.Error(), `references security definition "BearerToken"`)
+	}
+}
+
+func getCollidingConfig() *openapi.Config {
+	config, _ := getConfig(false)
+	getDefinitions := config.GetDefinitions
+	config.GetDefinitions = func(ref openapi.ReferenceCallback) map[string]openapi.OpenAPIDefinition {
+		defs := getDefinitions(ref)
+		other := *TestInput{}.OpenAPIDefinition()
+		other.Schema.Description = "Other test input"
+		defs["example.com/other/builder.TestInput"] = other
+		defs["example.com/other/builder.Holder"] = openapi.OpenAPIDefinition{
+			Schema: spec.Schema{
+				SchemaProps: spec.SchemaProps{
+					Properties: map[string]spec.Schema{
+						"input": {SchemaProps: spec.SchemaProps{Ref: ref("k8s.io/kube-openapi/pkg/builder.TestInput")}},
+					},
+				},
+			},
+			Dependencies: []string{"k8s.io/kube-openapi/pkg/builder.TestInput"},
+		}
+		return defs
+	}
+	return config
+}
+
+func TestBuildOpenAPISpecDefinitionNameCollision(t *testing.T) {
+	assert := assert.New(t)
+	_, err := BuildOpenAPIDefinitionsForResources(getCollidingConfig(), "k8s.io/kube-openapi/pkg/builder.TestInput")
+	if assert.Error(err) {
+		assert.Contains(err.Error(), `"example.com/other/builder.TestInput"`)
+		assert.Contains(err.Error(), `"k8s.io/kube-openapi/pkg/builder.TestInput"`)
+	}
+}
+
+func TestBuildOpenAPISpecDisambiguateDefinitionNames(t *testing.T) {
+	assert := assert.New(t)
+	config := getCollidingConfig()
+	config.DisambiguateDefinitionNames = true
+	getDefinitions, calls := config.GetDefinitions, 0
+	config.GetDefinitions = func(ref openapi.ReferenceCallback) map[string]openapi.OpenAPIDefinition {
+		calls++
+		return getDefinitions(ref)
+	}
+	swagger, err := BuildOpenAPIDefinitionsForResources(config, "example.com/other/builder.TestInput", "example.com/other/builder.Holder")
+	if !assert.NoError(err) {
+		return
+	}
+	// Holder references the TestInput of this package first, so it keeps the friendly name.
+	assert.Equal("Test input", swagger.Definitions["builder.TestInput"].Description)
+	assert.Equal("Other test input", swagger.Definitions["builder.TestInput_v2"].Description)
+	assert.Len(swagger.Definitions, 3)
+	assert.Equal(1, calls)
+	input := swagger.Definitions["builder.Holder"].Properties["input"]
+	assert.Equal("#/definitions/builder.TestInput", input.Ref.String())
+}
+
+func TestBuildOpenAPISpecDefinitionGetterNameCollision(t *testing.T) {
+	config, container, assert := setUp(t, false)
+	getDefinitions := config.GetDefinitions
+	config.GetDefinitions = func(ref openapi.ReferenceCallback) map[string]openapi.OpenAPIDefinition {
+		defs := getDefinitions(ref)
+		output := defs["k8s.io/kube-openapi/pkg/builder.TestOutput"]
+		output.Schema.Properties = map[string]spec.Schema{
+			"item": {SchemaProps: spec.SchemaProps{Ref: ref("example.com/other/builder.TestItem")}},
+		}
+		output.Dependencies = []string{"example.com/other/builder.TestItem"}
+		defs["k8s.io/kube-openapi/pkg/builder.TestOutput"] = output
+		defs["k8s.io/kube-openapi/pkg/builder/go_default_test.TestOutput"] = output
+		defs["example.com/other/builder.TestItem"] = openapi.OpenAPIDefinition{
+			Schema: spec.Schema{SchemaProps: spec.SchemaProps{Description: "Other test item"}},
+		}
+		return defs
+	}
+	// The registered definitions have unique names, but TestWrapper depends on the TestItem of this package.
+	ws := new(restful.WebService)
+	ws.Path("/wrapper")
+	ws.Route(ws.GET("/").
+		Operation("getWrapper").
+		Produces(restful.MIME_JSON).
+		Returns(200, "OK", &TestWrapper{}).
+		To(noOp))
+	container.Add(ws)
+
+	_, err := BuildOpenAPISpec(container.RegisteredWebServices(), config)
+	if assert.Error(err) {
+		assert.Contains(err.Error(), `definition name "builder.TestItem" is used for both`)
+		assert.Contains(err.Error(), `"example.com/other/builder.TestItem"`)
+		assert.Contains(err.Error(), `"k8s.io/kube-openapi/pkg/builder.TestItem"`)
+	}
+	_, err = BuildOpenAPISpecParallel(container.RegisteredWebServices(), config, 2)
+	if assert.Error(err) {
+		assert.Contains(err.Error(), `definition name "builder.TestItem" is used for both`)
+	}
+}
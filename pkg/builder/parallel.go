@@ -0,0 +1,256 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package builder
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+
+	restful "github.com/emicklei/go-restful"
+	"github.com/go-openapi/spec"
+
+	"k8s.io/kube-openapi/pkg/common"
+	"k8s.io/kube-openapi/pkg/util"
+)
+
+// BuildOpenAPISpecParallel builds the same spec as BuildOpenAPISpec, but builds the paths of the web services
+// and the dependency subtrees of the definitions they use with up to the given number of concurrent workers.
+// A worker count below 1 is treated as 1.
+//
+// The config's GetOperationIDAndTags and GetDefinitionName functions are called concurrently and must be safe
+// for concurrent use.
+func BuildOpenAPISpecParallel(webServices []*restful.WebService, config *common.Config, workers int) (*spec.Swagger, error) {
+	if workers < 1 {
+		workers = 1
+	}
+	o, err := newOpenAPI(config)
+	if err != nil {
+		return nil, err
+	}
+	// Definitions provided by OpenAPIDefinitionGetters are registered upfront, so the workers only read
+	// o.definitions and o.visitedTypes.
+	if err := o.registerRouteDefinitionGetters(webServices); err != nil {
+		return nil, err
+	}
+	if err := o.buildPathsParallel(webServices, workers); err != nil {
+		return nil, err
+	}
+	if err := o.buildUsedDefinitionsParallel(workers); err != nil {
+		return nil, err
+	}
+	return o.finalizeSwagger()
+}
+
+// webServiceResult is the outcome of building the paths of a single web service.
+type webServiceResult struct {
+	paths           map[string]spec.PathItem
+	pathDefinitions map[string]map[string]bool
+	operationIDs    map[string]string
+	err             error
+}
+
+// buildPathsParallel builds the paths of the web services concurrently, and merges them into the spec in
+// the order of the web services. Definitions are only recorded as used and must be built afterwards.
+func (o *openAPI) buildPathsParallel(webServices []*restful.WebService, workers int) error {
+	results := make([]webServiceResult, len(webServices))
+	parallelize(workers, len(webServices), func(i int) {
+		w := *o
+		w.deferDefinitions = true
+		w.swagger = &spec.Swagger{}
+		*w.swagger = *o.swagger
+		w.swagger.Paths = &spec.Paths{Paths: map[string]spec.PathItem{}}
+		r := &results[i]
+		r.operationIDs = map[string]string{}
+		r.paths, r.pathDefinitions, r.err = w.buildWebServicePaths(webServices[i], r.operationIDs)
+	})
+
+	operationIDs := map[string]string{}
+	for _, r := range results {
+		if r.err != nil {
+			return r.err
+		}
+		for _, path := range sortedKeys(r.paths) {
+			if _, exists := o.swagger.Paths.Paths[path]; exists {
+				return fmt.Errorf("duplicate webservice route has been found for path: %v", path)
+			}
+			o.swagger.Paths.Paths[path] = r.paths[path]
+			o.pathDefinitions[path] = r.pathDefinitions[path]
+		}
+		ids := make([]string, 0, len(r.operationIDs))
+		for id := range r.operationIDs {
+			ids = append(ids, id)
+		}
+		sort.Strings(ids)
+		for _, id := range ids {
+			if dpath, exists := operationIDs[id]; exists {
+				return fmt.Errorf("duplicate Operation ID %v for path %v and %v", id, dpath, r.operationIDs[id])
+			}
+			operationIDs[id] = r.operationIDs[id]
+		}
+	}
+	return nil
+}
+
+// definitionSubtree is a definition used by the paths, together with the dependencies first reached through it.
+type definitionSubtree struct {
+	// names are the canonical type names of the subtree, in depth-first order.
+	names   []string
+	schemas []spec.Schema
+}
+
+// buildUsedDefinitionsParallel builds all definitions used by the paths of the spec, and their transitive
+// dependencies. The subtrees of the definitions are split in the order of the paths and definition names,
+// built concurrently, and then added to the spec in that order, so that names are assigned deterministically.
+func (o *openAPI) buildUsedDefinitionsParallel(workers int) error {
+	paths := make([]string, 0, len(o.pathDefinitions))
+	for path := range o.pathDefinitions {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	var subtrees []*definitionSubtree
+	seen := map[string]bool{}
+	var collect func(name string, subtree *definitionSubtree)
+	collect = func(name string, subtree *definitionSubtree) {
+		if seen[name] {
+			return
+		}
+		seen[name] = true
+		subtree.names = append(subtree.names, name)
+		for _, dep := range o.definitions[name].Dependencies {
+			collect(dep, subtree)
+		}
+	}
+	for _, path := range paths {
+		names := make([]string, 0, len(o.pathDefinitions[path]))
+		for name := range o.pathDefinitions[path] {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if !seen[name] {
+				subtree := &definitionSubtree{}
+				collect(name, subtree)
+				subtrees = append(subtrees, subtree)
+			}
+		}
+	}
+
+	parallelize(workers, len(subtrees), func(i int) {
+		subtree := subtrees[i]
+		subtree.schemas = make([]spec.Schema, len(subtree.names))
+		for j, name := range subtree.names {
+			if item, ok := o.definitions[name]; ok {
+				subtree.schemas[j] = o.buildDefinition(name, item)
+			}
+		}
+	})
+
+	for _, subtree := range subtrees {
+		for i, name := range subtree.names {
+			if _, ok := o.definitions[name]; !ok {
+				return missingDefinitionError(name)
+			}
+			if _, err := o.assignDefinitionName(name); err != nil {
+				return err
+			}
+			// Another type with an identical definition might have been given the same name.
+			defName := o.definitionName(name)
+			if _, exists := o.swagger.Definitions[defName]; !exists {
+				o.swagger.Definitions[defName] = subtree.schemas[i]
+			}
+		}
+	}
+	return nil
+}
+
+// registerRouteDefinitionGetters registers the definitions provided by the samples of all routes that are
+// not ignored, in the order in which buildPaths would register them.
+func (o *openAPI) registerRouteDefinitionGetters(webServices []*restful.WebService) error {
+	pathsToIgnore := util.NewTrie(o.config.IgnorePrefixes)
+	register := func(sample interface{}) error {
+		return o.registerDefinitionGetters(reflect.TypeOf(sample))
+	}
+	for _, w := range webServices {
+		if pathsToIgnore.HasPrefix(w.RootPath()) {
+			continue
+		}
+		for _, route := range w.Routes() {
+			path := route.Path
+			if strings.HasSuffix(path, ":*}") {
+				path = path[:len(path)-3] + "}"
+			}
+			if pathsToIgnore.HasPrefix(path) {
+				continue
+			}
+			for _, resp := range sortedResponseErrors(route) {
+				if err := register(resp.Model); err != nil {
+					return err
+				}
+			}
+			if len(route.ResponseErrors) == 0 && route.WriteSample != nil {
+				if err := register(route.WriteSample); err != nil {
+					return err
+				}
+			}
+			for _, param := range route.ParameterDocs {
+				if param.Data().Kind == restful.BodyParameterKind {
+					if err := register(route.ReadSample); err != nil {
+						return err
+					}
+					break
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// parallelize calls f for every index in [0, n) with up to the given number of concurrent workers,
+// and returns when all calls have returned.
+func parallelize(workers, n int, f func(i int)) {
+	if workers > n {
+		workers = n
+	}
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				f(i)
+			}
+		}()
+	}
+	for i := 0; i < n; i++ {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+}
+
+func sortedKeys(paths map[string]spec.PathItem) []string {
+	keys := make([]string, 0, len(paths))
+	for k := range paths {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,130 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package builder
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/emicklei/go-restful"
+	"github.com/go-openapi/spec"
+	"github.com/stretchr/testify/assert"
+
+	openapi "k8s.io/kube-openapi/pkg/common"
+)
+
+func TestBuildOpenAPISpecParallel(t *testing.T) {
+	_, container, assert := setUp(t, true)
+	webServices := container.RegisteredWebServices()
+	for i := 0; i < 10; i++ {
+		webServices = append(webServices, getWrapperWebService(fmt.Sprintf("/wrapper%d", i)))
+	}
+
+	config, _ := getConfig(true)
+	expected, err := BuildOpenAPISpec(webServices, config)
+	if !assert.NoError(err) {
+		return
+	}
+	expectedJSON, err := json.Marshal(expected)
+	if !assert.NoError(err) {
+		return
+	}
+	for _, workers := range []int{0, 1, 3, 100} {
+		config, _ := getConfig(true)
+		actual, err := BuildOpenAPISpecParallel(webServices, config, workers)
+		if !assert.NoError(err, "workers: %d", workers) {
+			continue
+		}
+		actualJSON, err := json.Marshal(actual)
+		if !assert.NoError(err) {
+			continue
+		}
+		assert.Equal(string(expectedJSON), string(actualJSON), "workers: %d", workers)
+	}
+}
+
+func TestBuildOpenAPISpecParallelErrors(t *testing.T) {
+	assert := assert.New(t)
+	config, _ := getConfig(false)
+	_, err := BuildOpenAPISpecParallel([]*restful.WebService{getWrapperWebService("/a"), getWrapperWebService("/a")}, config, 2)
+	assert.EqualError(err, "duplicate webservice route has been found for path: /a/")
+
+	config, _ = getConfig(false)
+	ws := getWrapperWebService("/b")
+	conflicting := new(restful.WebService)
+	conflicting.Path("/c")
+	conflicting.Route(conflicting.GET("/").Operation("get/b").Returns(200, "OK", &TestWrapper{}).To(noOp))
+	_, err = BuildOpenAPISpecParallel([]*restful.WebService{ws, conflicting}, config, 2)
+	assert.EqualError(err, "duplicate Operation ID get/b for path /b/ and /c/")
+
+	config, _ = getConfig(false)
+	getDefinitions := config.GetDefinitions
+	config.GetDefinitions = func(ref openapi.ReferenceCallback) map[string]openapi.OpenAPIDefinition {
+		defs := getDefinitions(ref)
+		for _, name := range []string{"k8s.io/kube-openapi/pkg/builder.TestInput", "k8s.io/kube-openapi/pkg/builder/go_default_test.TestInput"} {
+			input := defs[name]
+			input.Dependencies = append(input.Dependencies, "example.com/api.Missing")
+			defs[name] = input
+		}
+		return defs
+	}
+	_, err = BuildOpenAPISpecParallel([]*restful.WebService{getWrapperWebService("/d")}, config, 2)
+	if assert.Error(err) {
+		assert.Contains(err.Error(), "cannot find model definition for example.com/api.Missing")
+	}
+}
+
+func TestBuildOpenAPISpecResponseOrder(t *testing.T) {
+	assert := assert.New(t)
+	// Both definition getters are renamed to the same name, so the assigned names depend on the order in
+	// which the responses are visited, which must be by status code.
+	for i := 0; i < 10; i++ {
+		for _, parallel := range []bool{false, true} {
+			config, _ := getConfig(false)
+			config.DisambiguateDefinitionNames = true
+			getDefinitionName := config.GetDefinitionName
+			config.GetDefinitionName = func(name string) (string, spec.Extensions) {
+				if name == "k8s.io/kube-openapi/pkg/builder.TestWrapper" || name == "k8s.io/kube-openapi/pkg/builder.TestItem" {
+					return "builder.Shared", nil
+				}
+				return getDefinitionName(name)
+			}
+			ws := new(restful.WebService)
+			ws.Path("/wrapper")
+			ws.Route(ws.GET("/").
+				Operation("getWrapper").
+				Produces(restful.MIME_JSON).
+				Returns(404, "Not found", TestItem{}).
+				Returns(200, "OK", TestWrapper{}).
+				To(noOp))
+
+			var swagger *spec.Swagger
+			var err error
+			if parallel {
+				swagger, err = BuildOpenAPISpecParallel([]*restful.WebService{ws}, config, 2)
+			} else {
+				swagger, err = BuildOpenAPISpec([]*restful.WebService{ws}, config)
+			}
+			if !assert.NoError(err) {
+				return
+			}
+			assert.Equal("Test wrapper", swagger.Definitions["builder.Shared"].Description, "parallel: %v", parallel)
+			assert.Equal("Test item", swagger.Definitions["builder.Shared_v2"].Description, "parallel: %v", parallel)
+		}
+	}
+}
@@ -0,0 +1,326 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package builder
+
+import (
+	"fmt"
+	"strings"
+
+	restful "github.com/emicklei/go-restful"
+	"github.com/go-openapi/spec"
+
+	"k8s.io/kube-openapi/pkg/common"
+	"k8s.io/kube-openapi/pkg/util"
+)
+
+// SpecVariant describes one of the specs built by BuildOpenAPISpecVariants. Unset fields
+// fall back to the values of the common.Config the variants are built with.
+type SpecVariant struct {
+	// Name identifies the variant in the result of BuildOpenAPISpecVariants.
+	Name string
+
+	// IgnorePrefixes lists path prefixes to drop from this variant, in addition to config.IgnorePrefixes.
+	// Definitions only used by the dropped paths are dropped as well.
+	IgnorePrefixes []string
+
+	// StripExtensionPrefixes lists vendor extension prefixes (e.g. "x-kubernetes-") to remove from
+	// paths and definitions of this variant. Use "x-" to remove all vendor extensions.
+	StripExtensionPrefixes []string
+
+	// Info replaces config.Info if not nil.
+	Info *spec.Info
+
+	// SecurityDefinitions and DefaultSecurity replace the ones of the config if SecurityDefinitions is not nil.
+	// A nil SecurityDefinitions inherits the config's, while a pointer to an empty map builds the variant
+	// without any security definitions and default security, whatever DefaultSecurity is.
+	SecurityDefinitions *spec.SecurityDefinitions
+	DefaultSecurity     []map[string][]string
+
+	// PostProcessSpec replaces config.PostProcessSpec if not nil. The spec passed in shares schemas
+	// with the other variants, so it must not be mutated in place below the top-level maps.
+	//
+	// If nil, config.PostProcessSpec is called once for every such variant, with the same restriction.
+	// It cannot tell the variants apart, so variant specific post-processing must be set here.
+	PostProcessSpec func(*spec.Swagger) (*spec.Swagger, error)
+}
+
+// BuildOpenAPISpecVariants builds one spec per variant, given a list of webservices and a common.Config
+// shared by all variants. Paths and definitions are built only once and are shared between the variants.
+// The result maps variant names to specs.
+func BuildOpenAPISpecVariants(webServices []*restful.WebService, config *common.Config, variants ...SpecVariant) (map[string]*spec.Swagger, error) {
+	o, err := newOpenAPI(config)
+	if err != nil {
+		return nil, err
+	}
+	if err := o.buildPaths(webServices); err != nil {
+		return nil, err
+	}
+	ret := make(map[string]*spec.Swagger, len(variants))
+	for _, v := range variants {
+		if _, exists := ret[v.Name]; exists {
+			return nil, fmt.Errorf("duplicate spec variant %q", v.Name)
+		}
+		swagger, err := o.buildVariant(&v)
+		if err != nil {
+			return nil, fmt.Errorf("failed to build spec variant %q: %v", v.Name, err)
+		}
+		ret[v.Name] = swagger
+	}
+	return ret, nil
+}
+
+// buildVariant derives the spec of the given variant from the already built paths and definitions.
+func (o *openAPI) buildVariant(v *SpecVariant) (*spec.Swagger, error) {
+	ret := &spec.Swagger{
+		SwaggerProps: o.swagger.SwaggerProps,
+	}
+	ret.Paths = &spec.Paths{Paths: make(map[string]spec.PathItem, len(o.swagger.Paths.Paths))}
+	ret.Definitions = spec.Definitions{}
+
+	pathsToIgnore := util.NewTrie(v.IgnorePrefixes)
+	used := map[string]bool{}
+	for path, pathItem := range o.swagger.Paths.Paths {
+		if pathsToIgnore.HasPrefix(path) {
+			continue
+		}
+		ret.Paths.Paths[path] = stripPathItemExtensions(pathItem, v.StripExtensionPrefixes)
+		for name := range o.pathDefinitions[path] {
+			o.collectDependencies(name, used)
+		}
+	}
+	for name := range used {
+		defName := o.definitionName(name)
+		ret.Definitions[defName] = stripSchemaExtensions(o.swagger.Definitions[defName], v.StripExtensionPrefixes)
+	}
+	if len(v.StripExtensionPrefixes) > 0 {
+		ret.Parameters = stripParametersExtensions(ret.Parameters, v.StripExtensionPrefixes)
+		ret.Responses = stripResponsesExtensions(ret.Responses, v.StripExtensionPrefixes)
+	}
+
+	if v.Info != nil {
+		ret.Info = v.Info
+	}
+	securityDefinitions, defaultSecurity := o.config.SecurityDefinitions, o.config.DefaultSecurity
+	if v.SecurityDefinitions != nil {
+		securityDefinitions, defaultSecurity = v.SecurityDefinitions, v.DefaultSecurity
+		if len(*v.SecurityDefinitions) == 0 {
+			securityDefinitions, defaultSecurity = new(spec.SecurityDefinitions), nil
+		}
+	}
+	postProcessSpec := o.config.PostProcessSpec
+	if v.PostProcessSpec != nil {
+		postProcessSpec = v.PostProcessSpec
+	}
+	return finalizeSwagger(ret, securityDefinitions, defaultSecurity, postProcessSpec)
+}
+
+// collectDependencies adds the given canonical type name and all its transitive dependencies to used.
+func (o *openAPI) collectDependencies(name string, used map[string]bool) {
+	if used[name] {
+		return
+	}
+	used[name] = true
+	for _, dep := range o.definitions[name].Dependencies {
+		o.collectDependencies(dep, used)
+	}
+}
+
+// stripExtensions returns the extensions without the keys matching any of the prefixes.
+// The input is returned if nothing has to be removed.
+func stripExtensions(extensions spec.Extensions, prefixes []string) spec.Extensions {
+	if len(extensions) == 0 || len(prefixes) == 0 {
+		return extensions
+	}
+	hasPrefix := func(k string) bool {
+		for _, p := range prefixes {
+			if strings.HasPrefix(strings.ToLower(k), strings.ToLower(p)) {
+				return true
+			}
+		}
+		return false
+	}
+	var ret spec.Extensions
+	for k := range extensions {
+		if hasPrefix(k) {
+			ret = spec.Extensions{}
+			break
+		}
+	}
+	if ret == nil {
+		return extensions
+	}
+	for k, v := range extensions {
+		if !hasPrefix(k) {
+			ret[k] = v
+		}
+	}
+	if len(ret) == 0 {
+		return nil
+	}
+	return ret
+}
+
+// stripSchemaExtensions removes matching vendor extensions from the schema and all its sub-schemas.
+// It does not mutate the input. Without prefixes, the input is returned.
+func stripSchemaExtensions(s spec.Schema, prefixes []string) spec.Schema {
+	if len(prefixes) == 0 {
+		return s
+	}
+	s.Extensions = stripExtensions(s.Extensions, prefixes)
+	stripMap := func(m map[string]spec.Schema) map[string]spec.Schema {
+		if m == nil {
+			return nil
+		}
+		ret := make(map[string]spec.Schema, len(m))
+		for k, v := range m {
+			ret[k] = stripSchemaExtensions(v, prefixes)
+		}
+		return ret
+	}
+	stripSlice := func(l []spec.Schema) []spec.Schema {
+		if l == nil {
+			return nil
+		}
+		ret := make([]spec.Schema, len(l))
+		for i := range l {
+			ret[i] = stripSchemaExtensions(l[i], prefixes)
+		}
+		return ret
+	}
+	stripPtr := func(p *spec.Schema) *spec.Schema {
+		if p == nil {
+			return nil
+		}
+		ret := stripSchemaExtensions(*p, prefixes)
+		return &ret
+	}
+	s.Properties = stripMap(s.Properties)
+	s.PatternProperties = stripMap(s.PatternProperties)
+	s.Definitions = stripMap(s.Definitions)
+	s.AllOf = stripSlice(s.AllOf)
+	s.AnyOf = stripSlice(s.AnyOf)
+	s.OneOf = stripSlice(s.OneOf)
+	s.Not = stripPtr(s.Not)
+	if s.Items != nil {
+		s.Items = &spec.SchemaOrArray{Schema: stripPtr(s.Items.Schema), Schemas: stripSlice(s.Items.Schemas)}
+	}
+	if s.AdditionalProperties != nil {
+		s.AdditionalProperties = &spec.SchemaOrBool{Allows: s.AdditionalProperties.Allows, Schema: stripPtr(s.AdditionalProperties.Schema)}
+	}
+	if s.AdditionalItems != nil {
+		s.AdditionalItems = &spec.SchemaOrBool{Allows: s.AdditionalItems.Allows, Schema: stripPtr(s.AdditionalItems.Schema)}
+	}
+	return s
+}
+
+// stripParameterExtensions removes matching vendor extensions from the parameter and its schema.
+func stripParameterExtensions(p spec.Parameter, prefixes []string) spec.Parameter {
+	p.Extensions = stripExtensions(p.Extensions, prefixes)
+	if p.Schema != nil {
+		s := stripSchemaExtensions(*p.Schema, prefixes)
+		p.Schema = &s
+	}
+	return p
+}
+
+// stripResponseExtensions removes matching vendor extensions from the schema of the response.
+func stripResponseExtensions(r spec.Response, prefixes []string) spec.Response {
+	if r.Schema != nil {
+		s := stripSchemaExtensions(*r.Schema, prefixes)
+		r.Schema = &s
+	}
+	return r
+}
+
+// stripParametersExtensions removes matching vendor extensions from the shared parameters of a spec.
+// It does not mutate the input.
+func stripParametersExtensions(params map[string]spec.Parameter, prefixes []string) map[string]spec.Parameter {
+	if params == nil {
+		return nil
+	}
+	ret := make(map[string]spec.Parameter, len(params))
+	for name, p := range params {
+		ret[name] = stripParameterExtensions(p, prefixes)
+	}
+	return ret
+}
+
+// stripResponsesExtensions removes matching vendor extensions from the shared responses of a spec.
+// It does not mutate the input.
+func stripResponsesExtensions(responses map[string]spec.Response, prefixes []string) map[string]spec.Response {
+	if responses == nil {
+		return nil
+	}
+	ret := make(map[string]spec.Response, len(responses))
+	for name, r := range responses {
+		ret[name] = stripResponseExtensions(r, prefixes)
+	}
+	return ret
+}
+
+// stripPathItemExtensions removes matching vendor extensions from the path item, its operations,
+// parameters and responses. It does not mutate the input. Without prefixes, the input is returned.
+func stripPathItemExtensions(pathItem spec.PathItem, prefixes []string) spec.PathItem {
+	if len(prefixes) == 0 {
+		return pathItem
+	}
+	stripParameters := func(params []spec.Parameter) []spec.Parameter {
+		if params == nil {
+			return nil
+		}
+		ret := make([]spec.Parameter, len(params))
+		for i, p := range params {
+			ret[i] = stripParameterExtensions(p, prefixes)
+		}
+		return ret
+	}
+	stripOperation := func(op *spec.Operation) *spec.Operation {
+		if op == nil {
+			return nil
+		}
+		ret := *op
+		ret.Extensions = stripExtensions(op.Extensions, prefixes)
+		ret.Parameters = stripParameters(op.Parameters)
+		if op.Responses != nil {
+			responses := *op.Responses
+			responses.Extensions = stripExtensions(responses.Extensions, prefixes)
+			if responses.Default != nil {
+				r := stripResponseExtensions(*responses.Default, prefixes)
+				responses.Default = &r
+			}
+			if responses.StatusCodeResponses != nil {
+				responses.StatusCodeResponses = make(map[int]spec.Response, len(op.Responses.StatusCodeResponses))
+				for code, r := range op.Responses.StatusCodeResponses {
+					responses.StatusCodeResponses[code] = stripResponseExtensions(r, prefixes)
+				}
+			}
+			ret.Responses = &responses
+		}
+		return &ret
+	}
+
+	pathItem.Extensions = stripExtensions(pathItem.Extensions, prefixes)
+	pathItem.Parameters = stripParameters(pathItem.Parameters)
+	pathItem.Get = stripOperation(pathItem.Get)
+	pathItem.Put = stripOperation(pathItem.Put)
+	pathItem.Post = stripOperation(pathItem.Post)
+	pathItem.Delete = stripOperation(pathItem.Delete)
+	pathItem.Options = stripOperation(pathItem.Options)
+	pathItem.Head = stripOperation(pathItem.Head)
+	pathItem.Patch = stripOperation(pathItem.Patch)
+	return pathItem
+}
@@ -0,0 +1,128 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package builder
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/emicklei/go-restful"
+	"github.com/go-openapi/spec"
+)
+
+func TestBuildOpenAPISpecVariants(t *testing.T) {
+	config, container, assert := setUp(t, true)
+	ws := new(restful.WebService)
+	ws.Path("/wrapper")
+	ws.Route(ws.GET("/").
+		Operation("getWrapper").
+		Produces(restful.MIME_JSON).
+		Metadata("x-kubernetes-action", "get").
+		Returns(200, "OK", &TestWrapper{}).
+		To(noOp))
+	container.Add(ws)
+
+	publicInfo := &spec.Info{InfoProps: spec.InfoProps{Title: "Public"}}
+	specs, err := BuildOpenAPISpecVariants(container.RegisteredWebServices(), config,
+		SpecVariant{Name: "full"},
+		SpecVariant{
+			Name:                   "public",
+			IgnorePrefixes:         []string{"/bar", "/foo"},
+			StripExtensionPrefixes: []string{"x-kubernetes-", "x-test"},
+			Info:                   publicInfo,
+		},
+	)
+	if !assert.NoError(err) {
+		return
+	}
+
+	config, container, _ = setUp(t, true)
+	container.Add(ws)
+	expected, err := BuildOpenAPISpec(container.RegisteredWebServices(), config)
+	if !assert.NoError(err) {
+		return
+	}
+	expectedJSON, err := json.Marshal(expected)
+	if !assert.NoError(err) {
+		return
+	}
+	fullJSON, err := json.Marshal(specs["full"])
+	if !assert.NoError(err) {
+		return
+	}
+	assert.Equal(string(expectedJSON), string(fullJSON))
+
+	public := specs["public"]
+	assert.Equal(publicInfo, public.Info)
+	assert.Len(public.Paths.Paths, 1)
+	if assert.Contains(public.Paths.Paths, "/wrapper/") {
+		assert.Empty(public.Paths.Paths["/wrapper/"].Get.Extensions)
+		assert.Equal("get", specs["full"].Paths.Paths["/wrapper/"].Get.Extensions["x-kubernetes-action"])
+	}
+	assert.Len(public.Definitions, 2)
+	for name, def := range public.Definitions {
+		assert.Empty(def.Extensions, "definition %s", name)
+	}
+	assert.NotEmpty(specs["full"].Definitions["builder.TestWrapper"].Extensions, "stripping must not mutate shared definitions")
+}
+
+func TestBuildOpenAPISpecVariantsDuplicateName(t *testing.T) {
+	config, container, assert := setUp(t, false)
+	_, err := BuildOpenAPISpecVariants(container.RegisteredWebServices(), config, SpecVariant{Name: "a"}, SpecVariant{Name: "a"})
+	assert.Error(err)
+}
+
+func TestBuildOpenAPISpecVariantsStripsSharedResponses(t *testing.T) {
+	config, container, assert := setUp(t, false)
+	schema := spec.Schema{}
+	schema.Description = "Not found"
+	schema.Extensions = spec.Extensions{"x-kubernetes-kind": "Status"}
+	config.ResponseDefinitions = map[string]spec.Response{
+		"NotFound": {ResponseProps: spec.ResponseProps{Description: "not found", Schema: &schema}},
+	}
+	specs, err := BuildOpenAPISpecVariants(container.RegisteredWebServices(), config,
+		SpecVariant{Name: "full"},
+		SpecVariant{Name: "public", StripExtensionPrefixes: []string{"x-kubernetes-"}},
+	)
+	if !assert.NoError(err) {
+		return
+	}
+	assert.Empty(specs["public"].Responses["NotFound"].Schema.Extensions)
+	assert.Equal("Not found", specs["public"].Responses["NotFound"].Schema.Description)
+	assert.Equal("Status", specs["full"].Responses["NotFound"].Schema.Extensions["x-kubernetes-kind"])
+}
+
+func TestBuildOpenAPISpecVariantsSecurityDefinitions(t *testing.T) {
+	config, container, assert := setUp(t, false)
+	config.SecurityDefinitions = &spec.SecurityDefinitions{"basic": spec.BasicAuth()}
+	config.DefaultSecurity = []map[string][]string{{"basic": {}}}
+	internal := &spec.SecurityDefinitions{"apiKey": spec.APIKeyAuth("key", "header")}
+	specs, err := BuildOpenAPISpecVariants(container.RegisteredWebServices(), config,
+		SpecVariant{Name: "inherit"},
+		SpecVariant{Name: "replace", SecurityDefinitions: internal, DefaultSecurity: []map[string][]string{{"apiKey": {}}}},
+		SpecVariant{Name: "none", SecurityDefinitions: &spec.SecurityDefinitions{}, DefaultSecurity: []map[string][]string{{"basic": {}}}},
+	)
+	if !assert.NoError(err) {
+		return
+	}
+	assert.Equal(*config.SecurityDefinitions, specs["inherit"].SecurityDefinitions)
+	assert.Equal(config.DefaultSecurity, specs["inherit"].Security)
+	assert.Equal(*internal, specs["replace"].SecurityDefinitions)
+	assert.Equal([]map[string][]string{{"apiKey": {}}}, specs["replace"].Security)
+	assert.Nil(specs["none"].SecurityDefinitions)
+	assert.Nil(specs["none"].Security)
+}
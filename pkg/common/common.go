@@ -39,6 +39,8 @@ type GetOpenAPIDefinitions func(ReferenceCallback) map[string]OpenAPIDefinition
 // the definition returned by it will be used, otherwise the auto-generated definitions will be used. See
 // GetOpenAPITypeFormat for more information about trade-offs of using this interface or GetOpenAPITypeFormat method when
 // possible.
+// The builder also picks up this interface on route sample objects (and the types they are composed of), so types
+// that are not part of the GetDefinitions registry can still contribute their definitions.
 type OpenAPIDefinitionGetter interface {
 	OpenAPIDefinition() *OpenAPIDefinition
 }
@@ -83,7 +85,12 @@ type Config struct {
 	// It is an optional function to customize model names.
 	GetDefinitionName func(name string) (string, spec.Extensions)
 
+	// DisambiguateDefinitionNames makes the builder append a _v<N> suffix to definition names returned by
+	// GetDefinitionName that are already used by a different definition. By default such collisions are an error.
+	DisambiguateDefinitionNames bool
+
 	// PostProcessSpec runs after the spec is ready to serve. It allows a final modification to the spec before serving.
+	// Common modifications can be composed with aggregator.ChainTransformers.
 	PostProcessSpec func(*spec.Swagger) (*spec.Swagger, error)
 
 	// SecurityDefinitions is list of all security definitions for OpenAPI service. If this is not nil, the user of config
@@ -93,30 +100,73 @@ type Config struct {
 	// DefaultSecurity for all operations. This will pass as spec.SwaggerProps.Security to OpenAPI.
 	// For most cases, this will be list of acceptable definitions in SecurityDefinitions.
 	DefaultSecurity []map[string][]string
+
+	// TypeFormats adds to or overrides the default Go type to OpenAPI type/format mapping (see
+	// DefaultTypeFormats). Types listed here are converted to simple schemas instead of definitions.
+	TypeFormats TypeFormatRegistry
+}
+
+// TypeFormat is the simple OpenAPI schema a Go type is converted to.
+type TypeFormat struct {
+	Type    string
+	Format  string
+	Pattern string
+}
+
+// TypeFormatRegistry maps Go type names (package path dot type name, e.g. time.Duration or
+// k8s.io/apimachinery/pkg/api/resource.Quantity, or a builtin name such as int64) to simple
+// OpenAPI schemas. Types found in the registry are never turned into definitions.
+type TypeFormatRegistry map[string]TypeFormat
+
+var defaultTypeFormats = TypeFormatRegistry{
+	"uint":        {Type: "integer", Format: "int32"},
+	"uint8":       {Type: "integer", Format: "byte"},
+	"uint16":      {Type: "integer", Format: "int32"},
+	"uint32":      {Type: "integer", Format: "int64"},
+	"uint64":      {Type: "integer", Format: "int64"},
+	"int":         {Type: "integer", Format: "int32"},
+	"int8":        {Type: "integer", Format: "byte"},
+	"int16":       {Type: "integer", Format: "int32"},
+	"int32":       {Type: "integer", Format: "int32"},
+	"int64":       {Type: "integer", Format: "int64"},
+	"byte":        {Type: "integer", Format: "byte"},
+	"float64":     {Type: "number", Format: "double"},
+	"float32":     {Type: "number", Format: "float"},
+	"bool":        {Type: "boolean"},
+	"time.Time":   {Type: "string", Format: "date-time"},
+	"string":      {Type: "string"},
+	"integer":     {Type: "integer"},
+	"number":      {Type: "number"},
+	"boolean":     {Type: "boolean"},
+	"[]byte":      {Type: "string", Format: "byte"}, // base64 encoded characters
+	"interface{}": {Type: "object"},
+}
+
+// DefaultTypeFormats returns a copy of the built-in registry used by GetOpenAPITypeFormat.
+func DefaultTypeFormats() TypeFormatRegistry {
+	return defaultTypeFormats.Extend(nil)
 }
 
-var schemaTypeFormatMap = map[string][]string{
-	"uint":        {"integer", "int32"},
-	"uint8":       {"integer", "byte"},
-	"uint16":      {"integer", "int32"},
-	"uint32":      {"integer", "int64"},
-	"uint64":      {"integer", "int64"},
-	"int":         {"integer", "int32"},
-	"int8":        {"integer", "byte"},
-	"int16":       {"integer", "int32"},
-	"int32":       {"integer", "int32"},
-	"int64":       {"integer", "int64"},
-	"byte":        {"integer", "byte"},
-	"float64":     {"number", "double"},
-	"float32":     {"number", "float"},
-	"bool":        {"boolean", ""},
-	"time.Time":   {"string", "date-time"},
-	"string":      {"string", ""},
-	"integer":     {"integer", ""},
-	"number":      {"number", ""},
-	"boolean":     {"boolean", ""},
-	"[]byte":      {"string", "byte"}, // base64 encoded characters
-	"interface{}": {"object", ""},
+// Extend returns a new registry containing the entries of r, added to or overridden by the
+// entries of other. Neither r nor other are modified.
+func (r TypeFormatRegistry) Extend(other TypeFormatRegistry) TypeFormatRegistry {
+	ret := make(TypeFormatRegistry, len(r)+len(other))
+	for k, v := range r {
+		ret[k] = v
+	}
+	for k, v := range other {
+		ret[k] = v
+	}
+	return ret
+}
+
+// Lookup returns the simple OpenAPI schema for the given Go type name, if one is registered.
+func (r TypeFormatRegistry) Lookup(typeName string) (TypeFormat, bool) {
+	tf, ok := r[typeName]
+	if !ok || tf.Type == "" {
+		return TypeFormat{}, false
+	}
+	return tf, true
 }
 
 // This function is a reference for converting go (or any custom type) to a simple open API type,format pair. There are
@@ -158,12 +208,14 @@ var schemaTypeFormatMap = map[string][]string{
 //           }
 // }
 //
+// To convert additional types without changing this package, see Config.TypeFormats for the builder
+// and the k8s:openapi-gen:type-format package tag for openapi-gen.
 func GetOpenAPITypeFormat(typeName string) (string, string) {
-	mapped, ok := schemaTypeFormatMap[typeName]
+	mapped, ok := defaultTypeFormats.Lookup(typeName)
 	if !ok {
 		return "", ""
 	}
-	return mapped[0], mapped[1]
+	return mapped.Type, mapped.Format
 }
 
 func EscapeJsonPointer(p string) string {
@@ -172,3 +224,10 @@ func EscapeJsonPointer(p string) string {
 	p = strings.Replace(p, "/", "~1", -1)
 	return p
 }
+
+// UnescapeJsonPointer reverts EscapeJsonPointer.
+func UnescapeJsonPointer(p string) string {
+	p = strings.Replace(p, "~1", "/", -1)
+	p = strings.Replace(p, "~0", "~", -1)
+	return p
+}
@@ -0,0 +1,37 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEscapeJsonPointer(t *testing.T) {
+	for _, tc := range []struct {
+		unescaped, escaped string
+	}{
+		{"io.k8s.api.core.v1.Pod", "io.k8s.api.core.v1.Pod"},
+		{"/api/v1/pods", "~1api~1v1~1pods"},
+		{"a~b/c", "a~0b~1c"},
+		{"~1", "~01"},
+	} {
+		assert.Equal(t, tc.escaped, EscapeJsonPointer(tc.unescaped))
+		assert.Equal(t, tc.unescaped, UnescapeJsonPointer(tc.escaped))
+	}
+}
@@ -0,0 +1,140 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/go-openapi/spec"
+)
+
+const responsesPrefix = "#/responses/"
+
+// ConfigErrors lists all problems found by Config.Validate.
+type ConfigErrors []error
+
+func (e ConfigErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return "invalid OpenAPI config: " + strings.Join(msgs, "; ")
+}
+
+// Validate checks the config for problems that would otherwise only surface deep inside spec
+// building, or not at all. It returns nil or a ConfigErrors with every problem found.
+func (c *Config) Validate() error {
+	var errs ConfigErrors
+
+	if c.GetDefinitions == nil {
+		errs = append(errs, fmt.Errorf("GetDefinitions must not be nil"))
+	} else if !c.DisambiguateDefinitionNames {
+		errs = append(errs, c.validateDefinitionNames()...)
+	}
+
+	if c.DefaultResponse != nil {
+		if err := c.validateResponseRef("DefaultResponse", c.DefaultResponse); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	codes := make([]int, 0, len(c.CommonResponses))
+	for code := range c.CommonResponses {
+		codes = append(codes, code)
+	}
+	sort.Ints(codes)
+	for _, code := range codes {
+		resp := c.CommonResponses[code]
+		if err := c.validateResponseRef(fmt.Sprintf("CommonResponses[%d]", code), &resp); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	for i, requirement := range c.DefaultSecurity {
+		names := make([]string, 0, len(requirement))
+		for name := range requirement {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if c.SecurityDefinitions == nil {
+				errs = append(errs, fmt.Errorf("DefaultSecurity[%d] references security definition %q, but SecurityDefinitions is nil", i, name))
+			} else if _, ok := (*c.SecurityDefinitions)[name]; !ok {
+				errs = append(errs, fmt.Errorf("DefaultSecurity[%d] references unknown security definition %q", i, name))
+			}
+		}
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
+
+// validateResponseRef checks that a response referencing a shared response points into ResponseDefinitions.
+func (c *Config) validateResponseRef(field string, resp *spec.Response) error {
+	ref := resp.Ref.String()
+	if ref == "" {
+		return nil
+	}
+	if !strings.HasPrefix(ref, responsesPrefix) {
+		return nil
+	}
+	name := ref[len(responsesPrefix):]
+	if _, ok := c.ResponseDefinitions[name]; !ok {
+		return fmt.Errorf("%s references undefined response %q, add it to ResponseDefinitions", field, name)
+	}
+	return nil
+}
+
+// validateDefinitionNames checks that GetDefinitionName does not map two different definitions
+// returned by GetDefinitions to the same name.
+func (c *Config) validateDefinitionNames() []error {
+	getDefinitionName := c.GetDefinitionName
+	if getDefinitionName == nil {
+		getDefinitionName = func(name string) (string, spec.Extensions) {
+			return name[strings.LastIndex(name, "/")+1:], nil
+		}
+	}
+	definitions := c.GetDefinitions(func(name string) spec.Ref {
+		defName, _ := getDefinitionName(name)
+		return spec.MustCreateRef("#/definitions/" + EscapeJsonPointer(defName))
+	})
+
+	names := make([]string, 0, len(definitions))
+	for name := range definitions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var errs []error
+	sources := map[string]string{}
+	for _, name := range names {
+		defName, _ := getDefinitionName(name)
+		if other, ok := sources[defName]; ok {
+			// Mapping identical definitions to the same name is harmless.
+			if !reflect.DeepEqual(definitions[name], definitions[other]) {
+				errs = append(errs, fmt.Errorf("GetDefinitionName maps both %q and %q to definition name %q", other, name, defName))
+			}
+			continue
+		}
+		sources[defName] = name
+	}
+	return errs
+}
@@ -0,0 +1,113 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"testing"
+
+	"github.com/go-openapi/spec"
+	"github.com/stretchr/testify/assert"
+)
+
+func testDefinitions(_ ReferenceCallback) map[string]OpenAPIDefinition {
+	return map[string]OpenAPIDefinition{
+		"k8s.io/api/core/v1.Pod": {
+			Schema: spec.Schema{SchemaProps: spec.SchemaProps{Description: "core pod"}},
+		},
+		"example.com/api/v1.Pod": {
+			Schema: spec.Schema{SchemaProps: spec.SchemaProps{Description: "example pod"}},
+		},
+		"k8s.io/api/core/v1.Status": {
+			Schema: spec.Schema{SchemaProps: spec.SchemaProps{Description: "status"}},
+		},
+		"k8s.io/api/core/vendor/v1.Status": {
+			Schema: spec.Schema{SchemaProps: spec.SchemaProps{Description: "status"}},
+		},
+	}
+}
+
+func TestValidateValidConfig(t *testing.T) {
+	config := &Config{
+		GetDefinitions: testDefinitions,
+		GetDefinitionName: func(name string) (string, spec.Extensions) {
+			return name, nil
+		},
+		ResponseDefinitions: map[string]spec.Response{
+			"NotFound": {ResponseProps: spec.ResponseProps{Description: "not found"}},
+		},
+		CommonResponses: map[int]spec.Response{
+			404: {Refable: spec.Refable{Ref: spec.MustCreateRef("#/responses/NotFound")}},
+		},
+		SecurityDefinitions: &spec.SecurityDefinitions{
+			"BearerToken": spec.APIKeyAuth("authorization", "header"),
+		},
+		DefaultSecurity: []map[string][]string{{"BearerToken": {}}},
+	}
+	assert.NoError(t, config.Validate())
+}
+
+func TestValidateReportsAllErrors(t *testing.T) {
+	config := &Config{
+		DefaultResponse: &spec.Response{Refable: spec.Refable{Ref: spec.MustCreateRef("#/responses/Default")}},
+		CommonResponses: map[int]spec.Response{
+			401: {Refable: spec.Refable{Ref: spec.MustCreateRef("#/responses/Unauthorized")}},
+		},
+		DefaultSecurity: []map[string][]string{{"BearerToken": {}}},
+	}
+	err := config.Validate()
+	if !assert.Error(t, err) {
+		return
+	}
+	assert.Equal(t, ConfigErrors{
+		errorString(`GetDefinitions must not be nil`),
+		errorString(`DefaultResponse references undefined response "Default", add it to ResponseDefinitions`),
+		errorString(`CommonResponses[401] references undefined response "Unauthorized", add it to ResponseDefinitions`),
+		errorString(`DefaultSecurity[0] references security definition "BearerToken", but SecurityDefinitions is nil`),
+	}, toErrorStrings(err.(ConfigErrors)))
+
+	config.SecurityDefinitions = &spec.SecurityDefinitions{}
+	err = config.Validate()
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), `DefaultSecurity[0] references unknown security definition "BearerToken"`)
+	}
+}
+
+func TestValidateDefinitionNameCollisions(t *testing.T) {
+	config := &Config{
+		GetDefinitions: testDefinitions,
+	}
+	err := config.Validate()
+	if !assert.Error(t, err) {
+		return
+	}
+	// v1.Status is mapped twice as well, but to identical definitions.
+	assert.Equal(t, ConfigErrors{
+		errorString(`GetDefinitionName maps both "example.com/api/v1.Pod" and "k8s.io/api/core/v1.Pod" to definition name "v1.Pod"`),
+	}, toErrorStrings(err.(ConfigErrors)))
+}
+
+type errorString string
+
+func (e errorString) Error() string { return string(e) }
+
+func toErrorStrings(errs ConfigErrors) ConfigErrors {
+	ret := make(ConfigErrors, len(errs))
+	for i, err := range errs {
+		ret[i] = errorString(err.Error())
+	}
+	return ret
+}
@@ -0,0 +1,444 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package diff
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/go-openapi/spec"
+
+	"k8s.io/kube-openapi/pkg/common"
+)
+
+// Severity classifies a change by its impact on clients.
+type Severity string
+
+const (
+	// Breaking changes can break existing clients, e.g. a removed operation or a newly required field.
+	Breaking Severity = "breaking"
+	// NonBreaking changes do not break existing clients, e.g. an added operation or optional field.
+	NonBreaking Severity = "non-breaking"
+	// Documentation changes only change descriptions, titles, summaries and examples.
+	Documentation Severity = "documentation"
+)
+
+// Change is a difference between two specs.
+type Change struct {
+	Severity Severity `json:"severity"`
+	// Pointer is the JSON pointer (RFC 6901) of the changed element in the old spec, or of the added element in the
+	// new spec. References are followed, so schemas of definitions used by operations are located under
+	// /definitions, and everything else under the operations using them.
+	Pointer string `json:"pointer"`
+	// Message describes the change.
+	Message string `json:"message"`
+}
+
+func (c Change) String() string {
+	return fmt.Sprintf("%s: %s: %s", c.Severity, c.Pointer, c.Message)
+}
+
+// Report lists the changes between two specs, sorted by pointer.
+type Report struct {
+	Changes []Change `json:"changes"`
+}
+
+// HasBreakingChanges returns true if any change is breaking.
+func (r *Report) HasBreakingChanges() bool {
+	for _, c := range r.Changes {
+		if c.Severity == Breaking {
+			return true
+		}
+	}
+	return false
+}
+
+// Filter returns a report with the changes selected by keep.
+func (r *Report) Filter(keep func(c Change) bool) *Report {
+	ret := &Report{}
+	for _, c := range r.Changes {
+		if keep(c) {
+			ret.Changes = append(ret.Changes, c)
+		}
+	}
+	return ret
+}
+
+// WithoutDocumentation returns a report without documentation changes.
+func (r *Report) WithoutDocumentation() *Report {
+	return r.Filter(func(c Change) bool { return c.Severity != Documentation })
+}
+
+// Compare compares the paths of two specs, and all definitions, shared parameters and shared responses used by
+// them, following references on both sides. The specs are not mutated.
+func Compare(oldSpec, newSpec *spec.Swagger) *Report {
+	c := &comparer{
+		old:               oldSpec,
+		new:               newSpec,
+		collecting:        true,
+		definitionUsage:   map[string]direction{},
+		resolvingSchemas:  map[[2]string]bool{},
+		comparedByPointer: map[string]bool{},
+	}
+	// The first pass finds out whether definitions are used in requests, responses or both, which decides
+	// about the severity of some changes. The second pass records the changes.
+	c.comparePaths()
+	c.collecting = false
+	c.comparePaths()
+	for _, name := range sortedKeys(c.definitionUsage) {
+		c.compareDefinitions(name, c.definitionUsage[name])
+	}
+	sort.SliceStable(c.changes, func(i, j int) bool {
+		if c.changes[i].Pointer != c.changes[j].Pointer {
+			return c.changes[i].Pointer < c.changes[j].Pointer
+		}
+		return c.changes[i].Message < c.changes[j].Message
+	})
+	return &Report{Changes: c.changes}
+}
+
+// direction is a bit set of the directions in which a schema is sent.
+type direction int
+
+const (
+	request direction = 1 << iota
+	response
+)
+
+// comparer collects the changes between two specs.
+type comparer struct {
+	old, new *spec.Swagger
+	changes  []Change
+
+	// collecting is set during the first pass, which only collects definitionUsage.
+	collecting bool
+	// definitionUsage are the directions in which definitions referenced by the same name on both sides are used.
+	definitionUsage map[string]direction
+	// resolvingSchemas are the pairs of references being compared structurally, to stop on cycles.
+	resolvingSchemas map[[2]string]bool
+	// comparedByPointer prevents reporting the same change twice, for definitions used through different references.
+	comparedByPointer map[string]bool
+}
+
+func (c *comparer) add(severity Severity, pointer, format string, args ...interface{}) {
+	if c.collecting {
+		return
+	}
+	c.changes = append(c.changes, Change{Severity: severity, Pointer: pointer, Message: fmt.Sprintf(format, args...)})
+}
+
+// severity returns Breaking if the change breaks clients using the schema in any of the directions it is used in,
+// and NonBreaking otherwise.
+func severity(used, breaksIn direction) Severity {
+	if used&breaksIn != 0 {
+		return Breaking
+	}
+	return NonBreaking
+}
+
+func (c *comparer) comparePaths() {
+	oldPaths, newPaths := pathItems(c.old), pathItems(c.new)
+	for _, path := range sortedKeys(oldPaths) {
+		pointer := pointerTo("/paths", path)
+		newItem, found := newPaths[path]
+		if !found {
+			c.add(Breaking, pointer, "path removed")
+			continue
+		}
+		oldItem := oldPaths[path]
+		c.comparePathItems(pointer, &oldItem, &newItem)
+	}
+	for _, path := range sortedKeys(newPaths) {
+		if _, found := oldPaths[path]; !found {
+			c.add(NonBreaking, pointerTo("/paths", path), "path added")
+		}
+	}
+}
+
+func pathItems(sp *spec.Swagger) map[string]spec.PathItem {
+	if sp.Paths == nil {
+		return nil
+	}
+	return sp.Paths.Paths
+}
+
+func operations(pathItem *spec.PathItem) map[string]*spec.Operation {
+	ret := map[string]*spec.Operation{}
+	for method, op := range map[string]*spec.Operation{
+		"get":     pathItem.Get,
+		"put":     pathItem.Put,
+		"post":    pathItem.Post,
+		"delete":  pathItem.Delete,
+		"options": pathItem.Options,
+		"head":    pathItem.Head,
+		"patch":   pathItem.Patch,
+	} {
+		if op != nil {
+			ret[method] = op
+		}
+	}
+	return ret
+}
+
+func (c *comparer) comparePathItems(pointer string, oldItem, newItem *spec.PathItem) {
+	oldOps, newOps := operations(oldItem), operations(newItem)
+	for _, method := range sortedKeys(oldOps) {
+		opPointer := pointerTo(pointer, method)
+		newOp, found := newOps[method]
+		if !found {
+			c.add(Breaking, opPointer, "operation %s removed", strings.ToUpper(method))
+			continue
+		}
+		c.compareOperations(pointer, opPointer, oldItem, newItem, oldOps[method], newOp)
+	}
+	for _, method := range sortedKeys(newOps) {
+		if _, found := oldOps[method]; !found {
+			c.add(NonBreaking, pointerTo(pointer, method), "operation %s added", strings.ToUpper(method))
+		}
+	}
+}
+
+func (c *comparer) compareOperations(pathPointer, pointer string, oldItem, newItem *spec.PathItem, oldOp, newOp *spec.Operation) {
+	if oldOp.ID != newOp.ID {
+		c.add(Breaking, pointer, "operation ID changed from %q to %q", oldOp.ID, newOp.ID)
+	}
+	if oldOp.Summary != newOp.Summary {
+		c.add(Documentation, pointer, "summary changed")
+	}
+	if oldOp.Description != newOp.Description {
+		c.add(Documentation, pointer, "description changed")
+	}
+	if oldOp.Deprecated != newOp.Deprecated {
+		if newOp.Deprecated {
+			c.add(NonBreaking, pointer, "operation deprecated")
+		} else {
+			c.add(NonBreaking, pointer, "operation no longer deprecated")
+		}
+	}
+	c.compareMediaTypes(pointer, "consumes", effective(oldOp.Consumes, c.old.Consumes), effective(newOp.Consumes, c.new.Consumes))
+	c.compareMediaTypes(pointer, "produces", effective(oldOp.Produces, c.old.Produces), effective(newOp.Produces, c.new.Produces))
+	c.compareParameters(pathPointer, pointer, oldItem, newItem, oldOp, newOp)
+	c.compareResponses(pointerTo(pointer, "responses"), oldOp.Responses, newOp.Responses)
+}
+
+func effective(opValues, defaultValues []string) []string {
+	if opValues != nil {
+		return opValues
+	}
+	return defaultValues
+}
+
+func (c *comparer) compareMediaTypes(pointer, field string, oldTypes, newTypes []string) {
+	removed, added := difference(oldTypes, newTypes)
+	if len(removed) > 0 {
+		c.add(Breaking, pointer, "%s no longer has %s", field, strings.Join(removed, ", "))
+	}
+	if len(added) > 0 {
+		c.add(NonBreaking, pointer, "%s has new %s", field, strings.Join(added, ", "))
+	}
+}
+
+// locatedParameter is a resolved parameter, and the pointer of where it is used.
+type locatedParameter struct {
+	param   spec.Parameter
+	pointer string
+}
+
+// effectiveParameters returns the resolved parameters of the operation, including the ones inherited from the path
+// item, by location and name. There is at most one body parameter, and its name does not appear in requests, so
+// it is keyed by its location only.
+func effectiveParameters(sp *spec.Swagger, pathPointer, pointer string, pathItem *spec.PathItem, op *spec.Operation) map[string]locatedParameter {
+	ret := map[string]locatedParameter{}
+	for _, l := range []struct {
+		pointer string
+		params  []spec.Parameter
+	}{
+		{pathPointer, pathItem.Parameters},
+		{pointer, op.Parameters},
+	} {
+		for i, p := range l.params {
+			if ref := p.Ref.String(); strings.HasPrefix(ref, parameterPrefix) {
+				if resolved, found := sp.Parameters[ref[len(parameterPrefix):]]; found {
+					p = resolved
+				}
+			}
+			key := p.In + "/" + p.Name
+			if p.In == "body" {
+				key = p.In
+			}
+			ret[key] = locatedParameter{param: p, pointer: pointerTo(l.pointer, "parameters", strconv.Itoa(i))}
+		}
+	}
+	return ret
+}
+
+func (c *comparer) compareParameters(pathPointer, pointer string, oldItem, newItem *spec.PathItem, oldOp, newOp *spec.Operation) {
+	oldParams := effectiveParameters(c.old, pathPointer, pointer, oldItem, oldOp)
+	newParams := effectiveParameters(c.new, pathPointer, pointer, newItem, newOp)
+	for _, k := range sortedKeys(oldParams) {
+		old := oldParams[k]
+		new, found := newParams[k]
+		if !found {
+			c.add(Breaking, old.pointer, "%s parameter %q removed", old.param.In, old.param.Name)
+			continue
+		}
+		c.compareParameter(old.pointer, &old.param, &new.param)
+	}
+	for _, k := range sortedKeys(newParams) {
+		if _, found := oldParams[k]; found {
+			continue
+		}
+		new := newParams[k]
+		if new.param.Required {
+			c.add(Breaking, new.pointer, "required %s parameter %q added", new.param.In, new.param.Name)
+		} else {
+			c.add(NonBreaking, new.pointer, "optional %s parameter %q added", new.param.In, new.param.Name)
+		}
+		if new.param.Schema != nil {
+			// Collect the definitions the new parameter uses.
+			c.compareSchemas(pointerTo(new.pointer, "schema"), new.param.Schema, new.param.Schema, request)
+		}
+	}
+}
+
+func (c *comparer) compareParameter(pointer string, old, new *spec.Parameter) {
+	if !old.Required && new.Required {
+		c.add(Breaking, pointer, "parameter became required")
+	} else if old.Required && !new.Required {
+		c.add(NonBreaking, pointer, "parameter became optional")
+	}
+	if old.Name != new.Name {
+		// Only body parameters can be renamed without breaking requests.
+		c.add(Documentation, pointer, "name changed from %q to %q", old.Name, new.Name)
+	}
+	if old.Description != new.Description {
+		c.add(Documentation, pointer, "description changed")
+	}
+	c.compareSimpleSchemas(pointer, &old.SimpleSchema, &new.SimpleSchema, &old.CommonValidations, &new.CommonValidations, request)
+	if old.Items != nil || new.Items != nil {
+		oldItems, newItems := old.Items, new.Items
+		if oldItems == nil {
+			oldItems = &spec.Items{}
+		}
+		if newItems == nil {
+			newItems = &spec.Items{}
+		}
+		c.compareSimpleSchemas(pointerTo(pointer, "items"), &oldItems.SimpleSchema, &newItems.SimpleSchema, &oldItems.CommonValidations, &newItems.CommonValidations, request)
+	}
+	c.compareSchemas(pointerTo(pointer, "schema"), old.Schema, new.Schema, request)
+}
+
+func (c *comparer) compareResponses(pointer string, old, new *spec.Responses) {
+	oldResponses, newResponses := c.responses(c.old, old), c.responses(c.new, new)
+	for _, code := range sortedKeys(oldResponses) {
+		respPointer := pointerTo(pointer, code)
+		newResp, found := newResponses[code]
+		if !found {
+			c.add(Breaking, respPointer, "response removed")
+			continue
+		}
+		oldResp := oldResponses[code]
+		if oldResp.Description != newResp.Description {
+			c.add(Documentation, respPointer, "description changed")
+		}
+		c.compareSchemas(pointerTo(respPointer, "schema"), oldResp.Schema, newResp.Schema, response)
+	}
+	for _, code := range sortedKeys(newResponses) {
+		if _, found := oldResponses[code]; found {
+			continue
+		}
+		newResp := newResponses[code]
+		c.add(NonBreaking, pointerTo(pointer, code), "response added")
+		if newResp.Schema != nil {
+			// Collect the definitions the new response uses.
+			c.compareSchemas(pointerTo(pointer, code, "schema"), newResp.Schema, newResp.Schema, response)
+		}
+	}
+}
+
+// responses returns the resolved responses by status code or "default".
+func (c *comparer) responses(sp *spec.Swagger, responses *spec.Responses) map[string]spec.Response {
+	ret := map[string]spec.Response{}
+	if responses == nil {
+		return ret
+	}
+	resolve := func(r spec.Response) spec.Response {
+		if ref := r.Ref.String(); strings.HasPrefix(ref, responsePrefix) {
+			if resolved, found := sp.Responses[ref[len(responsePrefix):]]; found {
+				return resolved
+			}
+		}
+		return r
+	}
+	if responses.Default != nil {
+		ret["default"] = resolve(*responses.Default)
+	}
+	for code, r := range responses.StatusCodeResponses {
+		ret[strconv.Itoa(code)] = resolve(r)
+	}
+	return ret
+}
+
+const (
+	definitionPrefix = "#/definitions/"
+	parameterPrefix  = "#/parameters/"
+	responsePrefix   = "#/responses/"
+)
+
+func pointerTo(pointer string, tokens ...string) string {
+	for _, t := range tokens {
+		pointer += "/" + common.EscapeJsonPointer(t)
+	}
+	return pointer
+}
+
+// difference returns the values only in old and only in new, sorted.
+func difference(old, new []string) (removed, added []string) {
+	oldSet, newSet := map[string]bool{}, map[string]bool{}
+	for _, v := range old {
+		oldSet[v] = true
+	}
+	for _, v := range new {
+		newSet[v] = true
+		if !oldSet[v] {
+			added = append(added, v)
+		}
+	}
+	for _, v := range old {
+		if !newSet[v] {
+			removed = append(removed, v)
+		}
+	}
+	sort.Strings(removed)
+	sort.Strings(added)
+	return unique(removed), unique(added)
+}
+
+func unique(sorted []string) []string {
+	if len(sorted) == 0 {
+		return nil
+	}
+	ret := sorted[:1]
+	for _, v := range sorted[1:] {
+		if v != ret[len(ret)-1] {
+			ret = append(ret, v)
+		}
+	}
+	return ret
+}
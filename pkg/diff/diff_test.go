@@ -0,0 +1,233 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package diff
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/go-openapi/spec"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func parseSpec(t *testing.T, s string) *spec.Swagger {
+	sp := &spec.Swagger{}
+	require.NoError(t, json.Unmarshal([]byte(s), sp))
+	return sp
+}
+
+// specWith returns a spec with a GET and a POST operation on /foo, which return and accept the Foo definition.
+func specWith(definitions string) string {
+	return `{
+  "swagger": "2.0",
+  "paths": {
+    "/foo": {
+      "get": {
+        "operationId": "getFoo",
+        "responses": {"200": {"description": "OK", "schema": {"$ref": "#/definitions/Foo"}}}
+      },
+      "post": {
+        "operationId": "createFoo",
+        "parameters": [{"name": "body", "in": "body", "required": true, "schema": {"$ref": "#/definitions/Bar"}}],
+        "responses": {"201": {"description": "Created"}}
+      }
+    }
+  },
+  "definitions": ` + definitions + `
+}`
+}
+
+func TestCompare(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		old, new string
+		expected []Change
+	}{
+		{
+			name:     "identical",
+			old:      specWith(`{"Foo": {"type": "object"}, "Bar": {"type": "object"}}`),
+			new:      specWith(`{"Foo": {"type": "object"}, "Bar": {"type": "object"}}`),
+			expected: nil,
+		},
+		{
+			name: "removed and added paths and operations",
+			old: `{"swagger": "2.0", "paths": {
+				"/a": {"get": {"responses": {"200": {"description": "OK"}}}},
+				"/b": {"get": {"responses": {"200": {"description": "OK"}}}, "delete": {"responses": {"200": {"description": "OK"}}}}
+			}}`,
+			new: `{"swagger": "2.0", "paths": {
+				"/b": {"get": {"responses": {"200": {"description": "OK"}}}, "put": {"responses": {"200": {"description": "OK"}}}},
+				"/c": {"get": {"responses": {"200": {"description": "OK"}}}}
+			}}`,
+			expected: []Change{
+				{Breaking, "/paths/~1a", "path removed"},
+				{Breaking, "/paths/~1b/delete", "operation DELETE removed"},
+				{NonBreaking, "/paths/~1b/put", "operation PUT added"},
+				{NonBreaking, "/paths/~1c", "path added"},
+			},
+		},
+		{
+			name: "removed and type-changed properties",
+			old:  specWith(`{"Foo": {"properties": {"a": {"type": "string"}, "b": {"type": "string"}}}, "Bar": {}}`),
+			new:  specWith(`{"Foo": {"properties": {"a": {"type": "integer"}, "c": {"type": "string"}}}, "Bar": {}}`),
+			expected: []Change{
+				{Breaking, "/definitions/Foo/properties/a", "type changed from string to integer"},
+				{Breaking, "/definitions/Foo/properties/b", `property "b" removed`},
+				{NonBreaking, "/definitions/Foo/properties/c", `property "c" added`},
+			},
+		},
+		{
+			name: "newly required fields break requests only",
+			old:  specWith(`{"Foo": {"properties": {"a": {}}}, "Bar": {"properties": {"a": {}}}}`),
+			new:  specWith(`{"Foo": {"properties": {"a": {}}, "required": ["a"]}, "Bar": {"properties": {"a": {}}, "required": ["a"]}}`),
+			expected: []Change{
+				{Breaking, "/definitions/Bar/properties/a", `property "a" became required`},
+				{NonBreaking, "/definitions/Foo/properties/a", `property "a" became required`},
+			},
+		},
+		{
+			name: "narrowed enum breaks requests, widened enum breaks responses",
+			old:  specWith(`{"Foo": {"enum": ["a", "b"]}, "Bar": {"enum": ["a", "b"]}}`),
+			new:  specWith(`{"Foo": {"enum": ["a"]}, "Bar": {"enum": ["a", "b", "c"]}}`),
+			expected: []Change{
+				{NonBreaking, "/definitions/Bar", `enum has new "c"`},
+				{NonBreaking, "/definitions/Foo", `enum no longer has "b"`},
+			},
+		},
+		{
+			name: "narrowed enum in a definition used in both directions",
+			old:  specWith(`{"Foo": {"properties": {"b": {"$ref": "#/definitions/Bar"}}}, "Bar": {"enum": ["a", "b"]}}`),
+			new:  specWith(`{"Foo": {"properties": {"b": {"$ref": "#/definitions/Bar"}}}, "Bar": {"enum": ["a"]}}`),
+			expected: []Change{
+				{Breaking, "/definitions/Bar", `enum no longer has "b"`},
+			},
+		},
+		{
+			name: "changed list type",
+			old:  specWith(`{"Foo": {"properties": {"l": {"type": "array", "x-kubernetes-list-type": "atomic"}}}, "Bar": {}}`),
+			new:  specWith(`{"Foo": {"properties": {"l": {"type": "array", "x-kubernetes-list-type": "set"}}}, "Bar": {}}`),
+			expected: []Change{
+				{Breaking, "/definitions/Foo/properties/l", `extension x-kubernetes-list-type changed from "atomic" to "set"`},
+			},
+		},
+		{
+			name: "documentation changes",
+			old:  specWith(`{"Foo": {"description": "old", "properties": {"a": {"title": "A"}}}, "Bar": {}}`),
+			new:  specWith(`{"Foo": {"description": "new", "properties": {"a": {"title": "The A"}}}, "Bar": {}}`),
+			expected: []Change{
+				{Documentation, "/definitions/Foo", "description changed"},
+				{Documentation, "/definitions/Foo/properties/a", "title changed"},
+			},
+		},
+		{
+			name: "renamed definition is compared structurally",
+			old:  specWith(`{"Foo": {"properties": {"a": {"type": "string"}}}, "Bar": {}}`),
+			new: `{"swagger": "2.0", "paths": {"/foo": {
+				"get": {"operationId": "getFoo", "responses": {"200": {"description": "OK", "schema": {"$ref": "#/definitions/Foo2"}}}},
+				"post": {"operationId": "createFoo", "parameters": [{"name": "body", "in": "body", "required": true, "schema": {"$ref": "#/definitions/Bar"}}], "responses": {"201": {"description": "Created"}}}
+			}}, "definitions": {"Foo2": {"properties": {"a": {"type": "integer"}}}, "Bar": {}}}`,
+			expected: []Change{
+				{Breaking, "/paths/~1foo/get/responses/200/schema/properties/a", "type changed from string to integer"},
+			},
+		},
+		{
+			name: "recursive definitions",
+			old:  specWith(`{"Foo": {"properties": {"next": {"$ref": "#/definitions/Foo"}, "a": {"maxLength": 5}}}, "Bar": {}}`),
+			new:  specWith(`{"Foo": {"properties": {"next": {"$ref": "#/definitions/Foo"}, "a": {"maxLength": 3}}}, "Bar": {}}`),
+			expected: []Change{
+				{NonBreaking, "/definitions/Foo/properties/a", "maxLength changed from 5 to 3"},
+			},
+		},
+		{
+			name: "parameters",
+			old: `{"swagger": "2.0", "paths": {"/foo": {
+				"parameters": [{"name": "pretty", "in": "query", "type": "boolean"}],
+				"get": {"parameters": [{"name": "limit", "in": "query", "type": "integer"}], "responses": {"200": {"description": "OK"}}}
+			}}}`,
+			new: `{"swagger": "2.0", "paths": {"/foo": {
+				"get": {"parameters": [
+					{"name": "limit", "in": "query", "type": "integer", "required": true},
+					{"name": "watch", "in": "query", "type": "boolean"},
+					{"$ref": "#/parameters/token"}
+				], "responses": {"200": {"description": "OK"}}}
+			}}, "parameters": {"token": {"name": "token", "in": "header", "type": "string", "required": true}}}`,
+			expected: []Change{
+				{Breaking, "/paths/~1foo/get/parameters/0", "parameter became required"},
+				{NonBreaking, "/paths/~1foo/get/parameters/1", `optional query parameter "watch" added`},
+				{Breaking, "/paths/~1foo/get/parameters/2", `required header parameter "token" added`},
+				{Breaking, "/paths/~1foo/parameters/0", `query parameter "pretty" removed`},
+			},
+		},
+		{
+			name: "renamed body parameter",
+			old:  specWith(`{"Foo": {}, "Bar": {"properties": {"a": {"type": "string"}}}}`),
+			new: `{"swagger": "2.0", "paths": {"/foo": {
+				"get": {"operationId": "getFoo", "responses": {"200": {"description": "OK", "schema": {"$ref": "#/definitions/Foo"}}}},
+				"post": {"operationId": "createFoo", "parameters": [{"name": "bar", "in": "body", "required": true, "schema": {"$ref": "#/definitions/Bar"}}], "responses": {"201": {"description": "Created"}}}
+			}}, "definitions": {"Foo": {}, "Bar": {"properties": {"a": {"type": "integer"}}}}}`,
+			expected: []Change{
+				{Breaking, "/definitions/Bar/properties/a", "type changed from string to integer"},
+				{Documentation, "/paths/~1foo/post/parameters/0", `name changed from "body" to "bar"`},
+			},
+		},
+		{
+			name: "responses",
+			old: `{"swagger": "2.0", "paths": {"/foo": {"get": {"responses": {
+				"200": {"description": "OK", "schema": {"type": "string"}},
+				"404": {"description": "Not found"}
+			}}}}}`,
+			new: `{"swagger": "2.0", "paths": {"/foo": {"get": {"responses": {
+				"200": {"description": "Found", "schema": {"type": "string", "format": "byte"}},
+				"default": {"description": "Error"}
+			}}}}}`,
+			expected: []Change{
+				{Documentation, "/paths/~1foo/get/responses/200", "description changed"},
+				{NonBreaking, "/paths/~1foo/get/responses/200/schema", `format "byte" added`},
+				{Breaking, "/paths/~1foo/get/responses/404", "response removed"},
+				{NonBreaking, "/paths/~1foo/get/responses/default", "response added"},
+			},
+		},
+		{
+			name: "removed definition",
+			old:  specWith(`{"Foo": {}, "Bar": {}}`),
+			new:  specWith(`{"Foo": {}}`),
+			expected: []Change{
+				{Breaking, "/definitions/Bar", "definition removed"},
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			oldSpec, newSpec := parseSpec(t, tc.old), parseSpec(t, tc.new)
+			report := Compare(oldSpec, newSpec)
+			assert.Equal(t, tc.expected, report.Changes)
+		})
+	}
+}
+
+func TestReport(t *testing.T) {
+	r := &Report{Changes: []Change{
+		{Documentation, "/definitions/Foo", "description changed"},
+		{NonBreaking, "/paths/~1foo", "path added"},
+	}}
+	assert.False(t, r.HasBreakingChanges())
+	assert.Equal(t, []Change{{NonBreaking, "/paths/~1foo", "path added"}}, r.WithoutDocumentation().Changes)
+
+	r.Changes = append(r.Changes, Change{Breaking, "/paths/~1bar", "path removed"})
+	assert.True(t, r.HasBreakingChanges())
+	assert.Equal(t, "breaking: /paths/~1bar: path removed", r.Changes[2].String())
+}
@@ -0,0 +1,472 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package diff
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/go-openapi/spec"
+)
+
+// breakingExtensions are the vendor extensions whose changes break clients, because they change how objects are
+// merged and patched.
+var breakingExtensions = map[string]bool{
+	"x-kubernetes-list-type":       true,
+	"x-kubernetes-list-map-keys":   true,
+	"x-kubernetes-map-type":        true,
+	"x-kubernetes-patch-strategy":  true,
+	"x-kubernetes-patch-merge-key": true,
+}
+
+// compareSchemas compares two schemas used in the given directions. References to the same definition are not
+// compared here, but recorded in definitionUsage during the first pass; the definitions are compared once after
+// the second pass. Other references are resolved and compared structurally.
+func (c *comparer) compareSchemas(pointer string, old, new *spec.Schema, used direction) {
+	if !c.collecting {
+		if c.comparedByPointer[pointer] {
+			return
+		}
+		c.comparedByPointer[pointer] = true
+	}
+	if old == nil && new == nil {
+		return
+	}
+	if old == nil {
+		c.add(severity(used, request), pointer, "schema added")
+		return
+	}
+	if new == nil {
+		c.add(severity(used, response), pointer, "schema removed")
+		return
+	}
+	oldRef, newRef := old.Ref.String(), new.Ref.String()
+	if oldRef != "" && oldRef == newRef && strings.HasPrefix(oldRef, definitionPrefix) {
+		c.useDefinition(oldRef[len(definitionPrefix):], used)
+		return
+	}
+	if oldRef != "" || newRef != "" {
+		key := [2]string{oldRef, newRef}
+		if c.resolvingSchemas[key] {
+			return
+		}
+		c.resolvingSchemas[key] = true
+		defer delete(c.resolvingSchemas, key)
+
+		var oldFound, newFound bool
+		old, oldFound = resolveSchema(c.old, old)
+		new, newFound = resolveSchema(c.new, new)
+		if !oldFound || !newFound {
+			if oldRef != newRef {
+				c.add(Breaking, pointer, "reference changed from %q to %q", oldRef, newRef)
+			}
+			return
+		}
+	}
+	c.compareResolvedSchemas(pointer, old, new, used)
+}
+
+// useDefinition records that the named definition is used in the given directions, and during the first pass
+// follows the definitions it references in turn.
+func (c *comparer) useDefinition(name string, used direction) {
+	if !c.collecting || c.definitionUsage[name]&used == used {
+		return
+	}
+	c.definitionUsage[name] |= used
+	oldDef, oldFound := c.old.Definitions[name]
+	newDef, newFound := c.new.Definitions[name]
+	if oldFound && newFound {
+		c.compareSchemas(pointerTo("/definitions", name), &oldDef, &newDef, used)
+	}
+}
+
+// compareDefinitions compares a definition referenced by the same name on both sides.
+func (c *comparer) compareDefinitions(name string, used direction) {
+	pointer := pointerTo("/definitions", name)
+	oldDef, oldFound := c.old.Definitions[name]
+	newDef, newFound := c.new.Definitions[name]
+	switch {
+	case oldFound && newFound:
+		c.compareSchemas(pointer, &oldDef, &newDef, used)
+	case oldFound:
+		c.add(Breaking, pointer, "definition removed")
+	case newFound:
+		c.add(NonBreaking, pointer, "definition added")
+	}
+}
+
+// resolveSchema returns the definition a schema refers to, or the schema itself if it is not a reference. It
+// returns false if the reference cannot be resolved.
+func resolveSchema(sp *spec.Swagger, s *spec.Schema) (*spec.Schema, bool) {
+	ref := s.Ref.String()
+	if ref == "" {
+		return s, true
+	}
+	if !strings.HasPrefix(ref, definitionPrefix) {
+		return nil, false
+	}
+	def, found := sp.Definitions[ref[len(definitionPrefix):]]
+	if !found {
+		return nil, false
+	}
+	return &def, true
+}
+
+func (c *comparer) compareResolvedSchemas(pointer string, old, new *spec.Schema, used direction) {
+	if old.Title != new.Title {
+		c.add(Documentation, pointer, "title changed")
+	}
+	if old.Description != new.Description {
+		c.add(Documentation, pointer, "description changed")
+	}
+	if !reflect.DeepEqual(old.Example, new.Example) {
+		c.add(Documentation, pointer, "example changed")
+	}
+	c.compareTypes(pointer, old.Type, new.Type, used)
+	c.compareFormats(pointer, old.Format, new.Format, used)
+	c.compareDefaults(pointer, old.Default, new.Default, used)
+	c.compareValidations(pointer, schemaValidations(old), schemaValidations(new), used)
+	c.compareExtensions(pointer, old.Extensions, new.Extensions)
+	c.compareProperties(pointer, old, new, used)
+
+	oldItems, oldTuple := itemsOf(old)
+	newItems, newTuple := itemsOf(new)
+	c.compareSchemas(pointerTo(pointer, "items"), oldItems, newItems, used)
+	c.compareSchemaLists(pointerTo(pointer, "items"), "items", oldTuple, newTuple, used)
+
+	if allowsAdditionalProperties(old) && !allowsAdditionalProperties(new) {
+		c.add(severity(used, request), pointer, "additional properties no longer allowed")
+	} else if !allowsAdditionalProperties(old) && allowsAdditionalProperties(new) {
+		c.add(severity(used, response), pointer, "additional properties allowed")
+	}
+	c.compareSchemas(pointerTo(pointer, "additionalProperties"), additionalPropertiesOf(old), additionalPropertiesOf(new), used)
+
+	c.compareSchemaLists(pointerTo(pointer, "allOf"), "allOf", old.AllOf, new.AllOf, used)
+	c.compareSchemaLists(pointerTo(pointer, "oneOf"), "oneOf", old.OneOf, new.OneOf, used)
+	c.compareSchemaLists(pointerTo(pointer, "anyOf"), "anyOf", old.AnyOf, new.AnyOf, used)
+}
+
+func (c *comparer) compareProperties(pointer string, old, new *spec.Schema, used direction) {
+	for _, name := range sortedKeys(old.Properties) {
+		propPointer := pointerTo(pointer, "properties", name)
+		newProp, found := new.Properties[name]
+		if !found {
+			c.add(Breaking, propPointer, "property %q removed", name)
+			continue
+		}
+		oldProp := old.Properties[name]
+		c.compareSchemas(propPointer, &oldProp, &newProp, used)
+	}
+	for _, name := range sortedKeys(new.Properties) {
+		if _, found := old.Properties[name]; found {
+			continue
+		}
+		propPointer := pointerTo(pointer, "properties", name)
+		c.add(NonBreaking, propPointer, "property %q added", name)
+		newProp := new.Properties[name]
+		// Collect the definitions the new property uses.
+		c.compareSchemas(propPointer, &newProp, &newProp, used)
+	}
+
+	removed, added := difference(old.Required, new.Required)
+	for _, name := range added {
+		c.add(severity(used, request), pointerTo(pointer, "properties", name), "property %q became required", name)
+	}
+	for _, name := range removed {
+		c.add(severity(used, response), pointerTo(pointer, "properties", name), "property %q no longer required", name)
+	}
+}
+
+// compareSchemaLists compares the schemas of allOf, oneOf, anyOf or tuple items by position.
+func (c *comparer) compareSchemaLists(pointer, field string, old, new []spec.Schema, used direction) {
+	for i := range old {
+		if i >= len(new) {
+			c.add(Breaking, pointerTo(pointer, strconv.Itoa(i)), "%s entry removed", field)
+			continue
+		}
+		c.compareSchemas(pointerTo(pointer, strconv.Itoa(i)), &old[i], &new[i], used)
+	}
+	for i := len(old); i < len(new); i++ {
+		c.add(Breaking, pointerTo(pointer, strconv.Itoa(i)), "%s entry added", field)
+		// Collect the definitions the new entry uses.
+		c.compareSchemas(pointerTo(pointer, strconv.Itoa(i)), &new[i], &new[i], used)
+	}
+}
+
+func itemsOf(s *spec.Schema) (*spec.Schema, []spec.Schema) {
+	if s.Items == nil {
+		return nil, nil
+	}
+	return s.Items.Schema, s.Items.Schemas
+}
+
+func allowsAdditionalProperties(s *spec.Schema) bool {
+	return s.AdditionalProperties == nil || s.AdditionalProperties.Allows || s.AdditionalProperties.Schema != nil
+}
+
+func additionalPropertiesOf(s *spec.Schema) *spec.Schema {
+	if s.AdditionalProperties == nil {
+		return nil
+	}
+	return s.AdditionalProperties.Schema
+}
+
+// compareSimpleSchemas compares the types and validations of non-body parameters or their items.
+func (c *comparer) compareSimpleSchemas(pointer string, old, new *spec.SimpleSchema, oldValidations, newValidations *spec.CommonValidations, used direction) {
+	c.compareTypes(pointer, nonEmpty(old.Type), nonEmpty(new.Type), used)
+	c.compareFormats(pointer, old.Format, new.Format, used)
+	c.compareDefaults(pointer, old.Default, new.Default, used)
+	if old.CollectionFormat != new.CollectionFormat {
+		c.add(Breaking, pointer, "collection format changed from %q to %q", old.CollectionFormat, new.CollectionFormat)
+	}
+	c.compareValidations(pointer, commonValidations(oldValidations), commonValidations(newValidations), used)
+}
+
+func nonEmpty(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return []string{s}
+}
+
+// compareTypes reports a changed type as breaking. Adding or removing types narrows or widens the accepted values.
+func (c *comparer) compareTypes(pointer string, old, new []string, used direction) {
+	removed, added := difference(old, new)
+	switch {
+	case len(removed) == 0 && len(added) == 0:
+	case len(old) == 0:
+		c.add(severity(used, request), pointer, "type %s added", strings.Join(added, ", "))
+	case len(new) == 0:
+		c.add(severity(used, response), pointer, "type %s removed", strings.Join(removed, ", "))
+	case len(added) == 0:
+		c.add(severity(used, request), pointer, "type no longer allows %s", strings.Join(removed, ", "))
+	case len(removed) == 0:
+		c.add(severity(used, response), pointer, "type allows %s", strings.Join(added, ", "))
+	default:
+		c.add(Breaking, pointer, "type changed from %s to %s", strings.Join(old, ", "), strings.Join(new, ", "))
+	}
+}
+
+func (c *comparer) compareFormats(pointer, old, new string, used direction) {
+	switch {
+	case old == new:
+	case old == "":
+		c.add(severity(used, request), pointer, "format %q added", new)
+	case new == "":
+		c.add(severity(used, response), pointer, "format %q removed", old)
+	default:
+		c.add(Breaking, pointer, "format changed from %q to %q", old, new)
+	}
+}
+
+func (c *comparer) compareDefaults(pointer string, old, new interface{}, used direction) {
+	if !reflect.DeepEqual(old, new) {
+		c.add(severity(used, request), pointer, "default changed from %s to %s", jsonString(old), jsonString(new))
+	}
+}
+
+func (c *comparer) compareExtensions(pointer string, old, new spec.Extensions) {
+	for _, name := range sortedKeys(old) {
+		newValue, found := new[name]
+		switch {
+		case !found:
+			c.add(extensionSeverity(name), pointer, "extension %s removed", name)
+		case !reflect.DeepEqual(old[name], newValue):
+			c.add(extensionSeverity(name), pointer, "extension %s changed from %s to %s", name, jsonString(old[name]), jsonString(newValue))
+		}
+	}
+	for _, name := range sortedKeys(new) {
+		if _, found := old[name]; !found {
+			c.add(extensionSeverity(name), pointer, "extension %s added", name)
+		}
+	}
+}
+
+func extensionSeverity(name string) Severity {
+	if breakingExtensions[strings.ToLower(name)] {
+		return Breaking
+	}
+	return NonBreaking
+}
+
+// validations are the validations shared by schemas, parameters and items.
+type validations struct {
+	maximum, minimum                   *float64
+	exclusiveMaximum, exclusiveMinimum bool
+	maxLength, minLength               *int64
+	maxItems, minItems                 *int64
+	maxProperties, minProperties       *int64
+	uniqueItems                        bool
+	multipleOf                         *float64
+	pattern                            string
+	enum                               []interface{}
+}
+
+func schemaValidations(s *spec.Schema) *validations {
+	return &validations{
+		maximum:          s.Maximum,
+		minimum:          s.Minimum,
+		exclusiveMaximum: s.ExclusiveMaximum,
+		exclusiveMinimum: s.ExclusiveMinimum,
+		maxLength:        s.MaxLength,
+		minLength:        s.MinLength,
+		maxItems:         s.MaxItems,
+		minItems:         s.MinItems,
+		maxProperties:    s.MaxProperties,
+		minProperties:    s.MinProperties,
+		uniqueItems:      s.UniqueItems,
+		multipleOf:       s.MultipleOf,
+		pattern:          s.Pattern,
+		enum:             s.Enum,
+	}
+}
+
+func commonValidations(v *spec.CommonValidations) *validations {
+	return &validations{
+		maximum:          v.Maximum,
+		minimum:          v.Minimum,
+		exclusiveMaximum: v.ExclusiveMaximum,
+		exclusiveMinimum: v.ExclusiveMinimum,
+		maxLength:        v.MaxLength,
+		minLength:        v.MinLength,
+		maxItems:         v.MaxItems,
+		minItems:         v.MinItems,
+		uniqueItems:      v.UniqueItems,
+		multipleOf:       v.MultipleOf,
+		pattern:          v.Pattern,
+		enum:             v.Enum,
+	}
+}
+
+// compareValidations reports validations narrowing the accepted values as breaking for requests, and validations
+// widening them as breaking for responses.
+func (c *comparer) compareValidations(pointer string, old, new *validations, used direction) {
+	narrowed := func(format string, args ...interface{}) {
+		c.add(severity(used, request), pointer, format, args...)
+	}
+	widened := func(format string, args ...interface{}) {
+		c.add(severity(used, response), pointer, format, args...)
+	}
+	compareBound := func(name string, old, new *float64, upper bool) {
+		switch {
+		case old == nil && new == nil:
+		case old == nil:
+			narrowed("%s %v added", name, *new)
+		case new == nil:
+			widened("%s %v removed", name, *old)
+		case *old == *new:
+		case (*new < *old) == upper:
+			narrowed("%s changed from %v to %v", name, *old, *new)
+		default:
+			widened("%s changed from %v to %v", name, *old, *new)
+		}
+	}
+	compareFlag := func(name string, old, new bool) {
+		if !old && new {
+			narrowed("%s added", name)
+		} else if old && !new {
+			widened("%s removed", name)
+		}
+	}
+
+	compareBound("maximum", old.maximum, new.maximum, true)
+	compareBound("minimum", old.minimum, new.minimum, false)
+	compareFlag("exclusiveMaximum", old.exclusiveMaximum, new.exclusiveMaximum)
+	compareFlag("exclusiveMinimum", old.exclusiveMinimum, new.exclusiveMinimum)
+	compareBound("maxLength", toFloat(old.maxLength), toFloat(new.maxLength), true)
+	compareBound("minLength", toFloat(old.minLength), toFloat(new.minLength), false)
+	compareBound("maxItems", toFloat(old.maxItems), toFloat(new.maxItems), true)
+	compareBound("minItems", toFloat(old.minItems), toFloat(new.minItems), false)
+	compareBound("maxProperties", toFloat(old.maxProperties), toFloat(new.maxProperties), true)
+	compareBound("minProperties", toFloat(old.minProperties), toFloat(new.minProperties), false)
+	compareFlag("uniqueItems", old.uniqueItems, new.uniqueItems)
+
+	switch {
+	case old.multipleOf == nil && new.multipleOf == nil:
+	case old.multipleOf == nil:
+		narrowed("multipleOf %v added", *new.multipleOf)
+	case new.multipleOf == nil:
+		widened("multipleOf %v removed", *old.multipleOf)
+	case *old.multipleOf != *new.multipleOf:
+		c.add(Breaking, pointer, "multipleOf changed from %v to %v", *old.multipleOf, *new.multipleOf)
+	}
+
+	switch {
+	case old.pattern == new.pattern:
+	case old.pattern == "":
+		narrowed("pattern %q added", new.pattern)
+	case new.pattern == "":
+		widened("pattern %q removed", old.pattern)
+	default:
+		c.add(Breaking, pointer, "pattern changed from %q to %q", old.pattern, new.pattern)
+	}
+
+	oldEnum, newEnum := jsonStrings(old.enum), jsonStrings(new.enum)
+	removed, added := difference(oldEnum, newEnum)
+	switch {
+	case len(removed) == 0 && len(added) == 0:
+	case len(oldEnum) == 0:
+		narrowed("enum %s added", strings.Join(added, ", "))
+	case len(newEnum) == 0:
+		widened("enum %s removed", strings.Join(removed, ", "))
+	default:
+		if len(removed) > 0 {
+			narrowed("enum no longer has %s", strings.Join(removed, ", "))
+		}
+		if len(added) > 0 {
+			widened("enum has new %s", strings.Join(added, ", "))
+		}
+	}
+}
+
+func toFloat(i *int64) *float64 {
+	if i == nil {
+		return nil
+	}
+	f := float64(*i)
+	return &f
+}
+
+func jsonString(v interface{}) string {
+	bs, err := json.Marshal(v)
+	if err != nil {
+		return "<invalid>"
+	}
+	return string(bs)
+}
+
+func jsonStrings(values []interface{}) []string {
+	var ret []string
+	for _, v := range values {
+		ret = append(ret, jsonString(v))
+	}
+	return ret
+}
+
+// sortedKeys returns the sorted keys of a map with string keys.
+func sortedKeys(m interface{}) []string {
+	values := reflect.ValueOf(m).MapKeys()
+	keys := make([]string, len(values))
+	for i, k := range values {
+		keys[i] = k.String()
+	}
+	sort.Strings(keys)
+	return keys
+}
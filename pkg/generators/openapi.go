@@ -23,6 +23,7 @@ import (
 	"path/filepath"
 	"reflect"
 	"sort"
+	"strconv"
 	"strings"
 
 	"k8s.io/gengo/generator"
@@ -37,6 +38,10 @@ import (
 const tagName = "k8s:openapi-gen"
 const tagOptional = "optional"
 
+// This is the package comment tag that maps additional Go types to simple OpenAPI types. Its value is
+// <go type>,<type>,<format>[,<pattern>], e.g. +k8s:openapi-gen:type-format=time.Duration,string,duration
+const tagTypeFormat = "k8s:openapi-gen:type-format"
+
 // Known values for the tag.
 const (
 	tagValueTrue  = "true"
@@ -65,6 +70,24 @@ func getSingleTagsValue(comments []string, tag string) (string, error) {
 	return tags[0], nil
 }
 
+// getTypeFormats returns the default type/format registry extended by the type-format tags in
+// the given (package) comments.
+func getTypeFormats(comments []string) (openapi.TypeFormatRegistry, error) {
+	extra := openapi.TypeFormatRegistry{}
+	for _, v := range types.ExtractCommentTags("+", comments)[tagTypeFormat] {
+		parts := strings.SplitN(v, ",", 4)
+		if len(parts) < 3 || parts[0] == "" || parts[1] == "" {
+			return nil, fmt.Errorf("invalid %s tag value %q, expected <go type>,<type>,<format>[,<pattern>]", tagTypeFormat, v)
+		}
+		tf := openapi.TypeFormat{Type: parts[1], Format: parts[2]}
+		if len(parts) == 4 {
+			tf.Pattern = parts[3]
+		}
+		extra[parts[0]] = tf
+	}
+	return openapi.DefaultTypeFormats().Extend(extra), nil
+}
+
 func hasOpenAPITagValue(comments []string, value string) bool {
 	tagValues := getOpenAPITagValue(comments)
 	for _, val := range tagValues {
@@ -223,6 +246,7 @@ type openAPITypeWriter struct {
 	*generator.SnippetWriter
 	context                *generator.Context
 	refTypes               map[string]*types.Type
+	typeFormats            openapi.TypeFormatRegistry
 	GetDefinitionInterface *types.Type
 }
 
@@ -231,6 +255,7 @@ func newOpenAPITypeWriter(sw *generator.SnippetWriter, c *generator.Context) ope
 		SnippetWriter: sw,
 		context:       c,
 		refTypes:      map[string]*types.Type{},
+		typeFormats:   openapi.DefaultTypeFormats(),
 	}
 }
 
@@ -322,6 +347,14 @@ func (g openAPITypeWriter) generate(t *types.Type) error {
 			return nil
 		}
 
+		if pkg := g.context.Universe.Package(t.Name.Package); pkg != nil {
+			typeFormats, err := getTypeFormats(pkg.Comments)
+			if err != nil {
+				return err
+			}
+			g.typeFormats = typeFormats
+		}
+
 		args := argsFromType(t)
 		g.Do("func "+nameTmpl+"(ref $.ReferenceCallback|raw$) $.OpenAPIDefinition|raw$ {\n", args)
 		if hasOpenAPIDefinitionMethods(t) {
@@ -373,7 +406,7 @@ func (g openAPITypeWriter) generate(t *types.Type) error {
 		deps := []string{}
 		for _, k := range keys {
 			v := g.refTypes[k]
-			if t, _ := openapi.GetOpenAPITypeFormat(v.String()); t != "" {
+			if _, ok := g.typeFormats.Lookup(v.String()); ok {
 				// This is a known type, we do not need a reference to it
 				// Will eliminate special case of time.Time
 				continue
@@ -531,15 +564,19 @@ func (g openAPITypeWriter) generateProperty(m *types.Member, parent *types.Type)
 	g.generateDescription(m.CommentLines)
 	jsonTags := getJsonTags(m)
 	if len(jsonTags) > 1 && jsonTags[1] == "string" {
-		g.generateSimpleProperty("string", "")
+		g.generateSimpleProperty(openapi.TypeFormat{Type: "string"})
 		g.Do("},\n},\n", nil)
 		return nil
 	}
-	t := resolveAliasAndPtrType(m.Type)
 	// If we can get a openAPI type and format for this type, we consider it to be simple property
-	typeString, format := openapi.GetOpenAPITypeFormat(t.String())
-	if typeString != "" {
-		g.generateSimpleProperty(typeString, format)
+	if tf, ok := g.lookupTypeFormat(m.Type); ok {
+		g.generateSimpleProperty(tf)
+		g.Do("},\n},\n", nil)
+		return nil
+	}
+	t := resolveAliasAndPtrType(m.Type)
+	if tf, ok := g.typeFormats.Lookup(t.String()); ok {
+		g.generateSimpleProperty(tf)
 		g.Do("},\n},\n", nil)
 		return nil
 	}
@@ -563,9 +600,12 @@ func (g openAPITypeWriter) generateProperty(m *types.Member, parent *types.Type)
 	return g.Error()
 }
 
-func (g openAPITypeWriter) generateSimpleProperty(typeString, format string) {
-	g.Do("Type: []string{\"$.$\"},\n", typeString)
-	g.Do("Format: \"$.$\",\n", format)
+func (g openAPITypeWriter) generateSimpleProperty(tf openapi.TypeFormat) {
+	g.Do("Type: []string{\"$.$\"},\n", tf.Type)
+	g.Do("Format: \"$.$\",\n", tf.Format)
+	if tf.Pattern != "" {
+		g.Do("Pattern: $.$,\n", strconv.Quote(tf.Pattern))
+	}
 }
 
 func (g openAPITypeWriter) generateReferenceProperty(t *types.Type) {
@@ -573,6 +613,26 @@ func (g openAPITypeWriter) generateReferenceProperty(t *types.Type) {
 	g.Do("Ref: ref(\"$.$\"),\n", t.Name.String())
 }
 
+// lookupTypeFormat returns the simple OpenAPI type for t, or for any of the types t is an alias
+// of or points to, whichever is registered first. This allows named types like time.Duration to be
+// registered even though they resolve to a builtin.
+func (g openAPITypeWriter) lookupTypeFormat(t *types.Type) (openapi.TypeFormat, bool) {
+	var prev *types.Type
+	for prev != t {
+		if tf, ok := g.typeFormats.Lookup(t.String()); ok {
+			return tf, true
+		}
+		prev = t
+		if t.Kind == types.Alias {
+			t = t.Underlying
+		}
+		if t.Kind == types.Pointer {
+			t = t.Elem
+		}
+	}
+	return openapi.TypeFormat{}, false
+}
+
 func resolveAliasAndPtrType(t *types.Type) *types.Type {
 	var prev *types.Type
 	for prev != t {
@@ -590,6 +650,7 @@ func resolveAliasAndPtrType(t *types.Type) *types.Type {
 func (g openAPITypeWriter) generateMapProperty(t *types.Type) error {
 	keyType := resolveAliasAndPtrType(t.Key)
 	elemType := resolveAliasAndPtrType(t.Elem)
+	elemTypeFormat, elemIsSimple := g.lookupTypeFormat(t.Elem)
 
 	// According to OpenAPI examples, only map from string is supported
 	if keyType.Name.Name != "string" {
@@ -597,9 +658,8 @@ func (g openAPITypeWriter) generateMapProperty(t *types.Type) error {
 	}
 	g.Do("Type: []string{\"object\"},\n", nil)
 	g.Do("AdditionalProperties: &spec.SchemaOrBool{\nAllows: true,\nSchema: &spec.Schema{\nSchemaProps: spec.SchemaProps{\n", nil)
-	typeString, format := openapi.GetOpenAPITypeFormat(elemType.String())
-	if typeString != "" {
-		g.generateSimpleProperty(typeString, format)
+	if elemIsSimple {
+		g.generateSimpleProperty(elemTypeFormat)
 		g.Do("},\n},\n},\n", nil)
 		return nil
 	}
@@ -619,11 +679,11 @@ func (g openAPITypeWriter) generateMapProperty(t *types.Type) error {
 
 func (g openAPITypeWriter) generateSliceProperty(t *types.Type) error {
 	elemType := resolveAliasAndPtrType(t.Elem)
+	elemTypeFormat, elemIsSimple := g.lookupTypeFormat(t.Elem)
 	g.Do("Type: []string{\"array\"},\n", nil)
 	g.Do("Items: &spec.SchemaOrArray{\nSchema: &spec.Schema{\nSchemaProps: spec.SchemaProps{\n", nil)
-	typeString, format := openapi.GetOpenAPITypeFormat(elemType.String())
-	if typeString != "" {
-		g.generateSimpleProperty(typeString, format)
+	if elemIsSimple {
+		g.generateSimpleProperty(elemTypeFormat)
 		g.Do("},\n},\n},\n", nil)
 		return nil
 	}
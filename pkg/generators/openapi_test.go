@@ -48,9 +48,15 @@ func construct(t *testing.T, files map[string]string, testNamer namer.Namer) (*p
 }
 
 func testOpenAPITypeWriter(t *testing.T, code string) (error, error, *assert.Assertions, *bytes.Buffer, *bytes.Buffer) {
+	return testOpenAPITypeWriterInFile(t, "bar.go", code)
+}
+
+// testOpenAPITypeWriterInFile is like testOpenAPITypeWriter, but allows to choose the file name, e.g.
+// doc.go for code with package comments.
+func testOpenAPITypeWriterInFile(t *testing.T, fileName, code string) (error, error, *assert.Assertions, *bytes.Buffer, *bytes.Buffer) {
 	assert := assert.New(t)
 	var testFiles = map[string]string{
-		"base/foo/bar.go": code,
+		"base/foo/" + fileName: code,
 	}
 	rawNamer := namer.NewRawNamer("o", nil)
 	namers := namer.NameSystems{
@@ -437,6 +443,99 @@ Format:foo.Blah{}.OpenAPISchemaFormat(),
 `, funcBuffer.String())
 }
 
+func TestTypeFormatTag(t *testing.T) {
+	callErr, funcErr, assert, _, funcBuffer := testOpenAPITypeWriterInFile(t, "doc.go", `
+// +k8s:openapi-gen:type-format=time.Duration,string,duration
+// +k8s:openapi-gen:type-format=base/foo.Color,string,,^#[0-9a-f]{6}$
+package foo
+
+import "time"
+
+type Color string
+
+// Blah is a test.
+type Blah struct {
+	// A duration
+	Timeout time.Duration
+	// Some colors
+	Colors []Color
+	// A map of colors
+	ColorMap map[string]*Color
+}
+`)
+	if callErr != nil {
+		t.Fatal(callErr)
+	}
+	if funcErr != nil {
+		t.Fatal(funcErr)
+	}
+	assert.Equal(`func schema_base_foo_Blah(ref common.ReferenceCallback) common.OpenAPIDefinition {
+return common.OpenAPIDefinition{
+Schema: spec.Schema{
+SchemaProps: spec.SchemaProps{
+Description: "Blah is a test.",
+Type: []string{"object"},
+Properties: map[string]spec.Schema{
+"Timeout": {
+SchemaProps: spec.SchemaProps{
+Description: "A duration",
+Type: []string{"string"},
+Format: "duration",
+},
+},
+"Colors": {
+SchemaProps: spec.SchemaProps{
+Description: "Some colors",
+Type: []string{"array"},
+Items: &spec.SchemaOrArray{
+Schema: &spec.Schema{
+SchemaProps: spec.SchemaProps{
+Type: []string{"string"},
+Format: "",
+Pattern: "^#[0-9a-f]{6}$",
+},
+},
+},
+},
+},
+"ColorMap": {
+SchemaProps: spec.SchemaProps{
+Description: "A map of colors",
+Type: []string{"object"},
+AdditionalProperties: &spec.SchemaOrBool{
+Allows: true,
+Schema: &spec.Schema{
+SchemaProps: spec.SchemaProps{
+Type: []string{"string"},
+Format: "",
+Pattern: "^#[0-9a-f]{6}$",
+},
+},
+},
+},
+},
+},
+Required: []string{"Timeout","Colors","ColorMap"},
+},
+},
+}
+}
+
+`, funcBuffer.String())
+}
+
+func TestInvalidTypeFormatTag(t *testing.T) {
+	_, funcErr, assert, _, _ := testOpenAPITypeWriterInFile(t, "doc.go", `
+// +k8s:openapi-gen:type-format=time.Duration
+package foo
+
+type Blah struct {
+	String string
+}
+`)
+	assert.Error(funcErr)
+}
+
 func TestPointer(t *testing.T) {
 	callErr, funcErr, assert, callBuffer, funcBuffer := testOpenAPITypeWriter(t, `
 package foo